@@ -1,55 +1,91 @@
 package cloudshare
 
 import (
+	"context"
 	"encoding/json"
 	"fmt"
 	"net/url"
 	"strconv"
 )
 
-func (c *Client) makeRequest(method string, path string, response interface{}, params *url.Values, jsonable interface{}) error {
+func (c *Client) makeRequest(ctx context.Context, method string, path string, response interface{}, params *url.Values, jsonable interface{}) error {
 	var body *string
 	if jsonable != nil {
 		buffer, err := json.Marshal(&jsonable)
 		if err != nil {
-			return APIError{
+			return &APIError{
 				InnerError: err,
 				Message:    "Failed to serialize request object to JSON",
+				Method:     method,
+				Path:       path,
 			}
 		}
 		bodyString := string(buffer)
 		body = &bodyString
 	}
-	res, err := c.Request(method, path, params, body)
+	res, err := c.RequestWithContext(ctx, method, path, params, body)
 	if err != nil {
 		return err
 	}
 	if response != nil {
 		e := json.Unmarshal(res.Body, &response)
-		// NOCOMMIT
-		// fmt.Println(path)
-		// fmt.Println(string(res.Body))
-		// fmt.Println("---------------------------------")
-		//
 		if e != nil {
-			return APIError{InnerError: e}
+			return &APIError{
+				InnerError: e,
+				Message:    "Failed to parse JSON response",
+				StatusCode: res.StatusCode,
+				Method:     method,
+				Path:       path,
+				Body:       res.Body,
+			}
 		}
 	}
 	return nil
 }
 
-func (c *Client) makeGetRequest(path string, response interface{}, params *url.Values) error {
-	return c.makeRequest("GET", path, response, params, nil)
+func (c *Client) makeGetRequest(ctx context.Context, path string, response interface{}, params *url.Values) error {
+	return c.makeRequest(ctx, "GET", path, response, params, nil)
+}
+
+func (c *Client) makePostRequest(ctx context.Context, path string, response interface{}, params *url.Values, jsonable interface{}) error {
+	return c.makeRequest(ctx, "POST", path, response, params, jsonable)
 }
 
-func (c *Client) makePostRequest(path string, response interface{}, params *url.Values, jsonable interface{}) error {
-	return c.makeRequest("POST", path, response, params, jsonable)
+/*
+Do invokes any API call and decodes the JSON response into a value of type T.
+Use it for endpoints that don't have a typed wrapper yet.
+
+	method: the HTTP method to use. e.g. "GET", "PUT"
+	path: the path relative to the API version, as in Request
+	query: optional url query params
+	body: optional request object, serialized to JSON
+
+Example:
+
+	type Class struct {
+		ID   string `json:"id"`
+		Name string `json:"name"`
+	}
+	class, err := cloudshare.Do[Class](ctx, client, "GET", "class/"+classID, nil, nil)
+*/
+func Do[T any](ctx context.Context, c *Client, method string, path string, query *url.Values, body interface{}) (T, error) {
+	var ret T
+	err := c.makeRequest(ctx, method, path, &ret, query, body)
+	return ret, err
 }
 
 // GetBlueprintDetails returns details about a blueprint
-func (c *Client) GetBlueprintDetails(projectID string, blueprintID string, ret *BlueprintDetails) error {
+func (c *Client) GetBlueprintDetails(projectID ProjectID, blueprintID BlueprintID, ret *BlueprintDetails) error {
+	return c.GetBlueprintDetailsWithContext(context.Background(), projectID, blueprintID, ret)
+}
+
+// GetBlueprintDetailsWithContext is GetBlueprintDetails bound to ctx
+func (c *Client) GetBlueprintDetailsWithContext(ctx context.Context, projectID ProjectID, blueprintID BlueprintID, ret *BlueprintDetails) error {
+	if err := validateIDs(projectID, blueprintID); err != nil {
+		return err
+	}
 	path := fmt.Sprintf("projects/%s/blueprints/%s", projectID, blueprintID)
-	return c.makeGetRequest(path, ret, nil)
+	return c.makeGetRequest(ctx, path, ret, nil)
 }
 
 /*
@@ -63,130 +99,262 @@ GetProjectsByFilter returns a list of projects for the user according to the fil
 		client.GetProjectsByFilter(["WhereUserIsProjectManager", "WhereUserCanCreateClass"], &projects)
 */
 func (c *Client) GetProjectsByFilter(filters []string, ret *[]Project) error {
+	return c.GetProjectsByFilterWithContext(context.Background(), filters, ret)
+}
+
+// GetProjectsByFilterWithContext is GetProjectsByFilter bound to ctx
+func (c *Client) GetProjectsByFilterWithContext(ctx context.Context, filters []string, ret *[]Project) error {
 	query := url.Values{}
 	for _, filter := range filters {
 		query.Add(filter, "true")
 	}
-	return c.makeGetRequest("projects", ret, &query)
+	return c.makeGetRequest(ctx, "projects", ret, &query)
 }
 
 // GetProjects returns a list of projects for the user
 func (c *Client) GetProjects(ret *[]Project) error {
-	return c.makeGetRequest("projects", ret, nil)
+	return c.GetProjectsWithContext(context.Background(), ret)
+}
+
+// GetProjectsWithContext is GetProjects bound to ctx
+func (c *Client) GetProjectsWithContext(ctx context.Context, ret *[]Project) error {
+	return c.makeGetRequest(ctx, "projects", ret, nil)
 }
 
 // GetProjectDetails returns project details by id
-func (c *Client) GetProjectDetails(projectID string, ret *ProjectDetails) error {
+func (c *Client) GetProjectDetails(projectID ProjectID, ret *ProjectDetails) error {
+	return c.GetProjectDetailsWithContext(context.Background(), projectID, ret)
+}
+
+// GetProjectDetailsWithContext is GetProjectDetails bound to ctx
+func (c *Client) GetProjectDetailsWithContext(ctx context.Context, projectID ProjectID, ret *ProjectDetails) error {
+	if err := projectID.Validate(); err != nil {
+		return err
+	}
 	path := fmt.Sprintf("projects/%s", projectID)
-	return c.makeGetRequest(path, ret, nil)
+	return c.makeGetRequest(ctx, path, ret, nil)
 }
 
 // GetBlueprints returns the blueprints available for a project
-func (c *Client) GetBlueprints(projectID string, ret *[]Blueprint) error {
+func (c *Client) GetBlueprints(projectID ProjectID, ret *[]Blueprint) error {
+	return c.GetBlueprintsWithContext(context.Background(), projectID, ret)
+}
+
+// GetBlueprintsWithContext is GetBlueprints bound to ctx
+func (c *Client) GetBlueprintsWithContext(ctx context.Context, projectID ProjectID, ret *[]Blueprint) error {
+	if err := projectID.Validate(); err != nil {
+		return err
+	}
 	path := fmt.Sprintf("projects/%s/blueprints", projectID)
-	return c.makeGetRequest(path, ret, nil)
+	return c.makeGetRequest(ctx, path, ret, nil)
 }
 
 // GetPolicies returns a list of all policies by project id
-func (c *Client) GetPolicies(projectID string, ret *[]Policy) error {
+func (c *Client) GetPolicies(projectID ProjectID, ret *[]Policy) error {
+	return c.GetPoliciesWithContext(context.Background(), projectID, ret)
+}
+
+// GetPoliciesWithContext is GetPolicies bound to ctx
+func (c *Client) GetPoliciesWithContext(ctx context.Context, projectID ProjectID, ret *[]Policy) error {
+	if err := projectID.Validate(); err != nil {
+		return err
+	}
 	path := fmt.Sprintf("projects/%s/policies", projectID)
-	return c.makeGetRequest(path, ret, nil)
+	return c.makeGetRequest(ctx, path, ret, nil)
 }
 
 func (c *Client) CreateProjectPolicy(request PolicyRequest, response *PolicyCreationResponse) error {
-	return c.makePostRequest("policies", response, nil, request)
+	return c.CreateProjectPolicyWithContext(context.Background(), request, response)
+}
+
+// CreateProjectPolicyWithContext is CreateProjectPolicy bound to ctx
+func (c *Client) CreateProjectPolicyWithContext(ctx context.Context, request PolicyRequest, response *PolicyCreationResponse) error {
+	if err := request.ProjectID.Validate(); err != nil {
+		return err
+	}
+	return c.makePostRequest(ctx, "policies", response, nil, request)
 }
 
 // GetEnvironments returns a list of environments, either in brief or full details
 // Possible criteria: allowed | allvisible
 func (c *Client) GetEnvironments(brief bool, criteria string, ret *Environments) error {
+	return c.GetEnvironmentsWithContext(context.Background(), brief, criteria, ret)
+}
+
+// GetEnvironmentsWithContext is GetEnvironments bound to ctx
+func (c *Client) GetEnvironmentsWithContext(ctx context.Context, brief bool, criteria string, ret *Environments) error {
 	query := url.Values{}
 	query.Add("brief", strconv.FormatBool(brief))
 	query.Add("criteria", criteria)
-	return c.makeGetRequest("envs", ret, &query)
+	return c.makeGetRequest(ctx, "envs", ret, &query)
 }
 
 // GetEnvironment returns a specific environment by ID
 // permission can be view|edit|owner
-func (c *Client) GetEnvironment(id string, permission string, ret *Environment) error {
+func (c *Client) GetEnvironment(id EnvironmentID, permission string, ret *Environment) error {
+	return c.GetEnvironmentWithContext(context.Background(), id, permission, ret)
+}
+
+// GetEnvironmentWithContext is GetEnvironment bound to ctx
+func (c *Client) GetEnvironmentWithContext(ctx context.Context, id EnvironmentID, permission string, ret *Environment) error {
+	if err := id.Validate(); err != nil {
+		return err
+	}
 	path := fmt.Sprintf("envs/%s", id)
 	query := url.Values{}
 	query.Add("permission", permission)
-	return c.makeGetRequest(path, ret, &query)
+	return c.makeGetRequest(ctx, path, ret, &query)
 }
 
 /* GetEnvironmentExtended returns extended information about an environment.
 See http://docs.cloudshare.com/rest-api/v3/environments/envs/actions-getextended/ */
-func (c *Client) GetEnvironmentExtended(id string, ret *EnvironmentExtended) error {
+func (c *Client) GetEnvironmentExtended(id EnvironmentID, ret *EnvironmentExtended) error {
+	return c.GetEnvironmentExtendedWithContext(context.Background(), id, ret)
+}
+
+// GetEnvironmentExtendedWithContext is GetEnvironmentExtended bound to ctx
+func (c *Client) GetEnvironmentExtendedWithContext(ctx context.Context, id EnvironmentID, ret *EnvironmentExtended) error {
+	if err := id.Validate(); err != nil {
+		return err
+	}
 	query := url.Values{}
-	query.Add("envId", id)
-	return c.makeGetRequest("envs/actions/getextended", ret, &query)
+	query.Add("envId", string(id))
+	return c.makeGetRequest(ctx, "envs/actions/getextended", ret, &query)
 }
 
 // EnvironmentCreateFromTemplate creates a new environment based on a VM template
 func (c *Client) EnvironmentCreateFromTemplate(request *EnvironmentTemplateRequest, response *CreateTemplateEnvResponse) error {
-	return c.makePostRequest("envs", response, nil, request)
+	return c.EnvironmentCreateFromTemplateWithContext(context.Background(), request, response)
+}
+
+// EnvironmentCreateFromTemplateWithContext is EnvironmentCreateFromTemplate bound to ctx
+func (c *Client) EnvironmentCreateFromTemplateWithContext(ctx context.Context, request *EnvironmentTemplateRequest, response *CreateTemplateEnvResponse) error {
+	return c.makePostRequest(ctx, "envs", response, nil, request)
 }
 
-func (c *Client) envPutAction(action string, params *url.Values) error {
-	return c.makeRequest("PUT", action, nil, params, nil)
+func (c *Client) envPutAction(ctx context.Context, action string, params *url.Values) error {
+	return c.makeRequest(ctx, "PUT", action, nil, params, nil)
 }
 
-func (c *Client) envPutActionByID(action string, id string) error {
+func (c *Client) envPutActionByID(ctx context.Context, action string, id EnvironmentID) error {
+	if err := id.Validate(); err != nil {
+		return err
+	}
 	query := url.Values{}
-	query.Add("envId", id)
-	return c.envPutAction("envs/actions/"+action, &query)
+	query.Add("envId", string(id))
+	return c.envPutAction(ctx, "envs/actions/"+action, &query)
 }
 
-func (c *Client) EnvironmentDelete(envID string) error {
-	return c.makeRequest("DELETE", fmt.Sprintf("envs/%s", envID), nil, nil, nil)
+func (c *Client) EnvironmentDelete(envID EnvironmentID) error {
+	return c.EnvironmentDeleteWithContext(context.Background(), envID)
+}
+
+// EnvironmentDeleteWithContext is EnvironmentDelete bound to ctx
+func (c *Client) EnvironmentDeleteWithContext(ctx context.Context, envID EnvironmentID) error {
+	if err := envID.Validate(); err != nil {
+		return err
+	}
+	return c.makeRequest(ctx, "DELETE", fmt.Sprintf("envs/%s", envID), nil, nil, nil)
 }
 
 // EnvironmentResume resumes a suspended environment
-func (c *Client) EnvironmentResume(envID string) error {
-	return c.envPutActionByID("resume", envID)
+func (c *Client) EnvironmentResume(envID EnvironmentID) error {
+	return c.EnvironmentResumeWithContext(context.Background(), envID)
+}
+
+// EnvironmentResumeWithContext is EnvironmentResume bound to ctx
+func (c *Client) EnvironmentResumeWithContext(ctx context.Context, envID EnvironmentID) error {
+	if err := c.validateAction(ctx, ActionResume, envID); err != nil {
+		return err
+	}
+	return c.envPutActionByID(ctx, "resume", envID)
 }
 
-func (c *Client) RebootVM(vmID string) error {
+func (c *Client) RebootVM(vmID VMID) error {
+	return c.RebootVMWithContext(context.Background(), vmID)
+}
+
+// RebootVMWithContext is RebootVM bound to ctx
+func (c *Client) RebootVMWithContext(ctx context.Context, vmID VMID) error {
+	if err := vmID.Validate(); err != nil {
+		return err
+	}
 	query := url.Values{}
-	query.Add("vmId", vmID)
-	return c.envPutAction("vms/actions/reboot", &query)
+	query.Add("vmId", string(vmID))
+	return c.envPutAction(ctx, "vms/actions/reboot", &query)
 }
 
 // EnvironmentSuspend suspends a running environment
-func (c *Client) EnvironmentSuspend(envID string) error {
-	return c.envPutActionByID("suspend", envID)
+func (c *Client) EnvironmentSuspend(envID EnvironmentID) error {
+	return c.EnvironmentSuspendWithContext(context.Background(), envID)
+}
+
+// EnvironmentSuspendWithContext is EnvironmentSuspend bound to ctx
+func (c *Client) EnvironmentSuspendWithContext(ctx context.Context, envID EnvironmentID) error {
+	if err := c.validateAction(ctx, ActionSuspend, envID); err != nil {
+		return err
+	}
+	return c.envPutActionByID(ctx, "suspend", envID)
 }
 
 // EnvironmentPostpone extends the environment's suspend time
-func (c *Client) EnvironmentPostpone(envID string) error {
-	return c.envPutActionByID("postpone", envID)
+func (c *Client) EnvironmentPostpone(envID EnvironmentID) error {
+	return c.EnvironmentPostponeWithContext(context.Background(), envID)
+}
+
+// EnvironmentPostponeWithContext is EnvironmentPostpone bound to ctx
+func (c *Client) EnvironmentPostponeWithContext(ctx context.Context, envID EnvironmentID) error {
+	if err := c.validateAction(ctx, ActionPostpone, envID); err != nil {
+		return err
+	}
+	return c.envPutActionByID(ctx, "postpone", envID)
 }
 
 // EnvironmentExtend extends the lifetime of an environment
-func (c *Client) EnvironmentExtend(envID string) error {
-	return c.envPutActionByID("extend", envID)
+func (c *Client) EnvironmentExtend(envID EnvironmentID) error {
+	return c.EnvironmentExtendWithContext(context.Background(), envID)
+}
+
+// EnvironmentExtendWithContext is EnvironmentExtend bound to ctx
+func (c *Client) EnvironmentExtendWithContext(ctx context.Context, envID EnvironmentID) error {
+	if err := c.validateAction(ctx, ActionExtend, envID); err != nil {
+		return err
+	}
+	return c.envPutActionByID(ctx, "extend", envID)
 }
 
 type EditVMHardwareRequest struct {
-	VMID          string      `json:"vmId"`
-	NumCPUs       interface{} `json:"numCpus"`
-	MemorySizeMBs interface{} `json:"memorySizeMBs"`
-	DiskSizeGBs   interface{} `json:"diskSizeGBs"`
+	VMID          VMID `json:"vmId"`
+	NumCPUs       *int `json:"numCpus"`
+	MemorySizeMBs *int `json:"memorySizeMBs"`
+	DiskSizeGBs   *int `json:"diskSizeGBs"`
 }
 
 type EditVMHardwareResponse struct {
-	ConflictsFound bool        `json:"conflictsFound"`
-	Conflicts      interface{} `json:"conflicts"`
+	ConflictsFound bool     `json:"conflictsFound"`
+	Conflicts      []string `json:"conflicts"`
 }
 
 func (c *Client) EditVMHardware(request EditVMHardwareRequest, response *EditVMHardwareResponse) error {
-	return c.makeRequest("PUT", "vms/actions/editvmhardware", response, nil, request)
+	return c.EditVMHardwareWithContext(context.Background(), request, response)
+}
+
+// EditVMHardwareWithContext is EditVMHardware bound to ctx
+func (c *Client) EditVMHardwareWithContext(ctx context.Context, request EditVMHardwareRequest, response *EditVMHardwareResponse) error {
+	if err := request.VMID.Validate(); err != nil {
+		return err
+	}
+	return c.makeRequest(ctx, "PUT", "vms/actions/editvmhardware", response, nil, request)
 }
 
 /* GetTemplates returns a list of available templates that can be filtered by GetTemplateParams
  */
 func (c *Client) GetTemplates(params *GetTemplateParams, ret *[]VMTemplate) error {
+	return c.GetTemplatesWithContext(context.Background(), params, ret)
+}
+
+// GetTemplatesWithContext is GetTemplates bound to ctx
+func (c *Client) GetTemplatesWithContext(ctx context.Context, params *GetTemplateParams, ret *[]VMTemplate) error {
 	query := url.Values{}
 	if params != nil {
 		if params.Skip != 0 {
@@ -196,18 +364,23 @@ func (c *Client) GetTemplates(params *GetTemplateParams, ret *[]VMTemplate) erro
 			query.Add("take", fmt.Sprintf("%d", params.Take))
 		}
 		if params.RegionID != "" {
-			query.Add("regionId", params.RegionID)
+			query.Add("regionId", string(params.RegionID))
 		}
 		if params.ProjectID != "" {
-			query.Add("projectId", params.ProjectID)
+			query.Add("projectId", string(params.ProjectID))
 		}
 		if params.TemplateType != "" {
 			query.Add("templateType", params.TemplateType)
 		}
 	}
-	return c.makeGetRequest("templates", ret, &query)
+	return c.makeGetRequest(ctx, "templates", ret, &query)
 }
 
 func (c *Client) GetRegions(ret *[]Region) error {
-	return c.makeGetRequest("regions", ret, nil)
+	return c.GetRegionsWithContext(context.Background(), ret)
+}
+
+// GetRegionsWithContext is GetRegions bound to ctx
+func (c *Client) GetRegionsWithContext(ctx context.Context, ret *[]Region) error {
+	return c.makeGetRequest(ctx, "regions", ret, nil)
 }
@@ -1,5 +1,7 @@
 package cloudshare
 
+import "context"
+
 func (envs *Environments) envByName(name string) *Environment {
 	for _, env := range *envs {
 		if env.Name == name {
@@ -12,18 +14,39 @@ func (envs *Environments) envByName(name string) *Environment {
 /* GetEnvironmentByName is a convenience function that searches for an environment by name
 and return nil if not found */
 func (c *Client) GetEnvironmentByName(name string) (*Environment, error) {
+	return c.GetEnvironmentByNameWithContext(context.Background(), name)
+}
+
+// GetEnvironmentByNameWithContext is GetEnvironmentByName bound to ctx
+func (c *Client) GetEnvironmentByNameWithContext(ctx context.Context, name string) (*Environment, error) {
 	allEnvs := Environments{}
-	apierr := c.GetEnvironments(true, "allvisible", &allEnvs)
+	apierr := c.GetEnvironmentsWithContext(ctx, true, "allvisible", &allEnvs)
 	if apierr != nil {
 		return nil, apierr
 	}
 	return allEnvs.envByName(name), nil
 }
 
-func EnvIDToURL(envID string) string {
-	return "https://use.cloudshare.com/Ent/Environment.mvc/View/" + envID[2:]
+// EnvIDToURL returns the web UI URL of an environment on use.cloudshare.com,
+// or "" if envID isn't a valid environment ID.
+// Use Client.Links to build URLs for the client's host.
+func EnvIDToURL(envID EnvironmentID) string {
+	links, _ := (&Client{}).Links()
+	ret, _ := links.Environment(envID)
+	return ret
+}
+
+// EnvIDToURL returns the web UI URL of an environment, on the host of the client's base URL
+func (c *Client) EnvIDToURL(envID EnvironmentID) (string, error) {
+	links, err := c.Links()
+	if err != nil {
+		return "", err
+	}
+	return links.Environment(envID)
 }
 
+// URL returns the environment's web UI URL on use.cloudshare.com, or "" if its ID is invalid.
+// Use Client.Links to build URLs for the client's host.
 func (e *Environment) URL() string {
 	return EnvIDToURL(e.ID)
 }
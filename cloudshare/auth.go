@@ -1,24 +1,88 @@
 package cloudshare
 
 import (
+	"crypto/rand"
 	"crypto/sha1"
 	"encoding/hex"
 	"fmt"
-	"math/rand"
+	"math/big"
+	"net/http"
 	"time"
 )
 
+// Signer authenticates an API request, typically by setting its Authorization header.
+// It's called for every attempt of every request, and must be safe for concurrent use.
+// A request's body, if any, can be read with request.GetBody. Signers that embed a timestamp
+// should add SigningOffset(request.Context()) to the local time.
+//
+// Implement it to use another authentication scheme, e.g. a bearer token:
+//
+//	client.Signer = cloudshare.SignerFunc(func(request *http.Request, creds cloudshare.Credentials) error {
+//		request.Header.Set("Authorization", "Bearer "+creds.APIKey)
+//		return nil
+//	})
+type Signer interface {
+	Sign(request *http.Request, creds Credentials) error
+}
+
+// SignerFunc adapts a function to a Signer
+type SignerFunc func(request *http.Request, creds Credentials) error
+
+func (f SignerFunc) Sign(request *http.Request, creds Credentials) error {
+	return f(request, creds)
+}
+
+// SHA1Signer signs requests with the cs_sha1 scheme: a SHA1 hash of the API key, the request URL,
+// a timestamp and a random nonce. It's the default Signer.
+// Timestamps are adjusted by SigningOffset, to compensate for the server's clock skew.
+// Now and Nonce can be replaced to make signatures deterministic, e.g. in tests.
+type SHA1Signer struct {
+	// Now defaults to time.Now
+	Now func() time.Time
+	// Nonce defaults to 10 random letters from crypto/rand
+	Nonce func() (string, error)
+}
+
+func (s SHA1Signer) Sign(request *http.Request, creds Credentials) error {
+	now := time.Now
+	if s.Now != nil {
+		now = s.Now
+	}
+	nonce := generateNonce
+	if s.Nonce != nil {
+		nonce = s.Nonce
+	}
+	token, err := nonce()
+	if err != nil {
+		return fmt.Errorf("cloudshare: failed to generate a nonce: %w", err)
+	}
+	request.Header.Set("Authorization", "cs_sha1 "+signToken(creds.APIKey, creds.APIID, request.URL.String(), now().Add(SigningOffset(request.Context())).Unix(), token))
+	return nil
+}
+
+func (c *Client) signer() Signer {
+	if c.Signer == nil {
+		return SHA1Signer{}
+	}
+	return c.Signer
+}
+
 var letters = []rune("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ")
 
-func randSeq(n int) string {
+func randSeq(n int) (string, error) {
 	b := make([]rune, n)
+	max := big.NewInt(int64(len(letters)))
 	for i := range b {
-		b[i] = letters[rand.Intn(len(letters))]
+		index, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", err
+		}
+		b[i] = letters[index.Int64()]
 	}
-	return string(b)
+	return string(b), nil
 }
 
-func generateToken() string {
+func generateNonce() (string, error) {
 	return randSeq(10)
 }
 
@@ -27,11 +91,8 @@ func hash(text string) string {
 	return hex.EncodeToString(s[:])
 }
 
-func authToken(apiKey string, apiID string, url string) string {
-	timestamp := time.Now().Unix()
-	token := generateToken()
+func signToken(apiKey string, apiID string, url string, timestamp int64, token string) string {
 	hmac := hash(fmt.Sprintf("%s%s%d%s", apiKey, url, timestamp, token))
-	ret := fmt.Sprintf("userapiid:%s;timestamp:%d;token:%s;hmac:%s",
+	return fmt.Sprintf("userapiid:%s;timestamp:%d;token:%s;hmac:%s",
 		apiID, timestamp, token, hmac)
-	return ret
 }
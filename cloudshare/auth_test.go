@@ -1,17 +1,80 @@
 package cloudshare
 
 import (
+	"errors"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/url"
 	"testing"
+	"time"
 )
 
-func TestGenerateToken(t *testing.T) {
-	token := generateToken()
-	assert.Equal(t, len(token), 10, "expecting token to be 10 chars long")
+func TestGenerateNonce(t *testing.T) {
+	token, err := generateNonce()
+	require.NoError(t, err)
+	assert.Regexp(t, "^[a-zA-Z]{10}$", token)
+
+	other, err := generateNonce()
+	require.NoError(t, err)
+	assert.NotEqual(t, token, other)
+}
+
+func TestSignToken(t *testing.T) {
+	actual := signToken("api_key", "api_id", "url", 1500000000, "abcdefghij")
+	assert.Equal(t, "userapiid:api_id;timestamp:1500000000;token:abcdefghij;hmac:"+hash("api_keyurl1500000000abcdefghij"), actual)
+}
+
+func TestSHA1SignerDeterministic(t *testing.T) {
+	signer := SHA1Signer{
+		Now:   func() time.Time { return time.Unix(1500000000, 0) },
+		Nonce: func() (string, error) { return "abcdefghij", nil },
+	}
+	u, _ := url.Parse("https://use.cloudshare.com/api/v3/projects?x=1")
+	request := &http.Request{URL: u, Header: http.Header{}}
+	require.NoError(t, signer.Sign(request, Credentials{APIID: "api_id", APIKey: "api_key"}))
+
+	expected := "cs_sha1 userapiid:api_id;timestamp:1500000000;token:abcdefghij;hmac:" +
+		hash("api_keyhttps://use.cloudshare.com/api/v3/projects?x=11500000000abcdefghij")
+	assert.Equal(t, expected, request.Header.Get("Authorization"))
 }
 
-func TestAuthToken(t *testing.T) {
-	actual := authToken("api_key", "api_id", "url")
-	expectedPattern := "userapiid:api_id;timestamp:\\d+;token:[a-zA-Z0-9]{10};hmac:[0-9a-f]+"
-	assert.Regexp(t, expectedPattern, actual)
+func TestSHA1SignerDefaults(t *testing.T) {
+	u, _ := url.Parse("https://use.cloudshare.com/api/v3/projects")
+	request := &http.Request{URL: u, Header: http.Header{}}
+	require.NoError(t, SHA1Signer{}.Sign(request, Credentials{APIID: "api_id", APIKey: "api_key"}))
+	assert.Regexp(t, `^cs_sha1 userapiid:api_id;timestamp:\d+;token:[a-zA-Z]{10};hmac:[0-9a-f]{40}$`, request.Header.Get("Authorization"))
+}
+
+func TestSHA1SignerNonceError(t *testing.T) {
+	boom := errors.New("boom")
+	signer := SHA1Signer{Nonce: func() (string, error) { return "", boom }}
+	u, _ := url.Parse("https://use.cloudshare.com/api/v3/projects")
+	err := signer.Sign(&http.Request{URL: u, Header: http.Header{}}, Credentials{})
+	assert.True(t, errors.Is(err, boom))
+}
+
+func TestCustomSigner(t *testing.T) {
+	var authorization []string
+	c := newLocalClient(t, func(w http.ResponseWriter, r *http.Request) {
+		authorization = append(authorization, r.Header.Get("Authorization"))
+		w.Write([]byte(`[]`))
+	})
+	c.APIID, c.APIKey = "api_id", "api_key"
+	c.Signer = SignerFunc(func(request *http.Request, creds Credentials) error {
+		request.Header.Set("Authorization", "Bearer "+creds.APIKey)
+		return nil
+	})
+	_, err := c.Request("GET", "projects", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"Bearer api_key"}, authorization)
+
+	c.Signer = SignerFunc(func(request *http.Request, creds Credentials) error {
+		return errors.New("no signature for you")
+	})
+	_, err = c.Request("GET", "projects", nil, nil)
+	var apiErr *APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, "Failed to sign request", apiErr.Message)
+	assert.Len(t, authorization, 1, "unsigned requests aren't sent")
 }
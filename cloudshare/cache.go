@@ -0,0 +1,323 @@
+package cloudshare
+
+import (
+	"container/list"
+	"errors"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CacheEntry is a cached API response
+type CacheEntry struct {
+	// Path is the API path of the request, e.g. "projects/PR123/blueprints"
+	Path       string
+	StatusCode int
+	Headers    http.Header
+	Body       []byte
+	// Expires is when the response is no longer fresh, and must be revalidated or fetched again
+	Expires time.Time
+}
+
+func (e *CacheEntry) response() *APIResponse {
+	return &APIResponse{
+		StatusCode: e.StatusCode,
+		Headers:    e.Headers.Clone(),
+		Body:       append([]byte(nil), e.Body...),
+	}
+}
+
+// CacheStore stores the entries of a ResponseCache. Implementations must be safe for concurrent use.
+// See NewMemoryCache and DiskCache.
+type CacheStore interface {
+	Get(key string) (*CacheEntry, bool)
+	Set(key string, entry *CacheEntry)
+	Delete(key string)
+	// Keys returns the keys of all the stored entries
+	Keys() []string
+}
+
+// pathLister is implemented by stores that can list the paths of their entries, by key, without loading them.
+// ResponseCache indexes the paths of other stores itself.
+type pathLister interface {
+	Paths() map[string]string
+}
+
+// DefaultCacheSize is the number of entries kept by the default in-memory store of a ResponseCache
+const DefaultCacheSize = 1000
+
+// DefaultCacheTTLs returns the TTLs of the catalog endpoints: regions, templates, and projects
+// with their blueprints and policies
+func DefaultCacheTTLs() map[string]time.Duration {
+	return map[string]time.Duration{
+		"regions":   time.Hour,
+		"templates": 10 * time.Minute,
+		"projects":  5 * time.Minute,
+	}
+}
+
+/*
+ResponseCache caches the responses of GET requests to slowly-changing endpoints,
+keyed on the request URL and API ID.
+
+Fresh responses are returned without sending a request. Once a response expires, it's revalidated with
+If-None-Match and If-Modified-Since when the server sent an ETag or Last-Modified header.
+Mutating requests (POST, PUT, DELETE...) invalidate the cached responses whose path contains
+their first path segment, e.g. creating a policy (POST policies) invalidates "projects/PR123/policies".
+Call Invalidate to drop other entries, e.g. after changes made outside the client. Responses to requests
+that were in flight during an invalidation aren't stored. Invalidation doesn't load the entries: the cache
+indexes their paths, reading the entries kept from before (e.g. by a DiskCache) once, on first use.
+
+A ResponseCache is safe for concurrent use, and must not be copied or modified once in use.
+Share one between clients to share the cached responses.
+
+Example:
+
+	cache := &cloudshare.ResponseCache{Store: cloudshare.DiskCache{Dir: ".cloudshare-cache"}}
+	client, err := cloudshare.NewClient(cloudshare.WithCache(cache))
+*/
+type ResponseCache struct {
+	// Store defaults to NewMemoryCache(DefaultCacheSize)
+	Store CacheStore
+	// TTLs maps the path prefixes of cached endpoints to how long their responses stay fresh.
+	// The longest matching prefix applies. GET requests to other paths aren't cached. Defaults to DefaultCacheTTLs().
+	TTLs map[string]time.Duration
+
+	once  sync.Once
+	store CacheStore
+	ttls  map[string]time.Duration
+
+	mu sync.Mutex
+	// paths maps the keys of the stored entries to their paths, unless the store is a pathLister
+	paths map[string]string
+	// generation is incremented by every invalidation, so that responses to requests sent before it aren't stored
+	generation uint64
+}
+
+func (rc *ResponseCache) init() {
+	rc.once.Do(func() {
+		rc.store = rc.Store
+		if rc.store == nil {
+			rc.store = NewMemoryCache(DefaultCacheSize)
+		}
+		rc.ttls = rc.TTLs
+		if rc.ttls == nil {
+			rc.ttls = DefaultCacheTTLs()
+		}
+		if _, ok := rc.store.(pathLister); !ok {
+			// index the entries kept from before, e.g. by a DiskCache, once
+			rc.paths = map[string]string{}
+			for _, key := range rc.store.Keys() {
+				if entry, ok := rc.store.Get(key); ok {
+					rc.paths[key] = entry.Path
+				}
+			}
+		}
+	})
+}
+
+// ttl returns how long responses of path stay fresh, and false if they aren't cached
+func (rc *ResponseCache) ttl(path string) (time.Duration, bool) {
+	rc.init()
+	path = strings.Trim(path, "/")
+	var ttl time.Duration
+	longest := -1
+	for prefix, d := range rc.ttls {
+		prefix = strings.Trim(prefix, "/")
+		if hasPathPrefix(path, prefix) && len(prefix) > longest {
+			ttl, longest = d, len(prefix)
+		}
+	}
+	return ttl, longest >= 0
+}
+
+func hasPathPrefix(path string, prefix string) bool {
+	return prefix == "" || path == prefix || strings.HasPrefix(path, prefix+"/")
+}
+
+// cached returns how long the responses of a request stay fresh, and false if they aren't cached.
+// A nil cache caches nothing.
+func (rc *ResponseCache) cached(method string, path string) (time.Duration, bool) {
+	if rc == nil || method != "GET" {
+		return 0, false
+	}
+	return rc.ttl(path)
+}
+
+// Invalidate drops the cached responses of paths starting with prefix. An empty prefix drops everything.
+func (rc *ResponseCache) Invalidate(prefix string) {
+	rc.invalidate(func(path string) bool {
+		return hasPathPrefix(path, strings.Trim(prefix, "/"))
+	})
+}
+
+func (rc *ResponseCache) invalidate(match func(path string) bool) {
+	rc.init()
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.generation++
+	paths := rc.paths
+	if lister, ok := rc.store.(pathLister); ok {
+		paths = lister.Paths()
+	}
+	for key, path := range paths {
+		if match(path) {
+			rc.store.Delete(key)
+			delete(rc.paths, key)
+		}
+	}
+}
+
+// set stores an entry, unless the cache was invalidated since generation
+func (rc *ResponseCache) set(key string, entry *CacheEntry, generation uint64) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if rc.generation != generation {
+		return
+	}
+	rc.store.Set(key, entry)
+	if rc.paths != nil {
+		rc.paths[key] = entry.Path
+	}
+}
+
+// invalidateAfter drops the cached responses that a request to path may have changed, if it's mutating
+func (rc *ResponseCache) invalidateAfter(method string, path string) {
+	if rc == nil || isReadMethod(method) {
+		return
+	}
+	resource, _, _ := strings.Cut(strings.Trim(path, "/"), "/")
+	rc.invalidate(func(path string) bool {
+		for _, segment := range strings.Split(path, "/") {
+			if segment == resource {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// do returns the cached response of a GET request if it's fresh, and otherwise calls send,
+// with conditional headers when a stale response can be revalidated
+func (rc *ResponseCache) do(key string, path string, ttl time.Duration, send func(header http.Header) (*APIResponse, error)) (*APIResponse, error) {
+	rc.init()
+	entry, cached := rc.store.Get(key)
+	if cached && time.Now().Before(entry.Expires) {
+		return entry.response(), nil
+	}
+
+	header := http.Header{}
+	if cached {
+		if etag := entry.Headers.Get("ETag"); etag != "" {
+			header.Set("If-None-Match", etag)
+		}
+		if modified := entry.Headers.Get("Last-Modified"); modified != "" {
+			header.Set("If-Modified-Since", modified)
+		}
+	}
+	rc.mu.Lock()
+	generation := rc.generation
+	rc.mu.Unlock()
+	res, err := send(header)
+
+	var apiErr *APIError
+	if cached && len(header) > 0 && errors.As(err, &apiErr) && apiErr.StatusCode == http.StatusNotModified {
+		refreshed := *entry
+		refreshed.Expires = time.Now().Add(ttl)
+		rc.set(key, &refreshed, generation)
+		return refreshed.response(), nil
+	}
+	if err == nil {
+		rc.set(key, &CacheEntry{
+			Path:       strings.Trim(path, "/"),
+			StatusCode: res.StatusCode,
+			Headers:    res.Headers.Clone(),
+			Body:       append([]byte(nil), res.Body...),
+			Expires:    time.Now().Add(ttl),
+		}, generation)
+	}
+	return res, err
+}
+
+func isReadMethod(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS":
+		return true
+	}
+	return false
+}
+
+// NewMemoryCache returns an in-memory store that keeps up to maxEntries entries,
+// evicting the least recently used ones
+func NewMemoryCache(maxEntries int) CacheStore {
+	return &memoryCache{max: maxEntries, entries: map[string]*list.Element{}, lru: list.New()}
+}
+
+type memoryCache struct {
+	max     int
+	mu      sync.Mutex
+	entries map[string]*list.Element
+	lru     *list.List
+}
+
+type memoryCacheItem struct {
+	key   string
+	entry *CacheEntry
+}
+
+func (m *memoryCache) Get(key string) (*CacheEntry, bool) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	element, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+	m.lru.MoveToFront(element)
+	return element.Value.(*memoryCacheItem).entry, true
+}
+
+func (m *memoryCache) Set(key string, entry *CacheEntry) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if element, ok := m.entries[key]; ok {
+		element.Value.(*memoryCacheItem).entry = entry
+		m.lru.MoveToFront(element)
+		return
+	}
+	m.entries[key] = m.lru.PushFront(&memoryCacheItem{key: key, entry: entry})
+	for m.max > 0 && m.lru.Len() > m.max {
+		oldest := m.lru.Back()
+		m.lru.Remove(oldest)
+		delete(m.entries, oldest.Value.(*memoryCacheItem).key)
+	}
+}
+
+func (m *memoryCache) Delete(key string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if element, ok := m.entries[key]; ok {
+		m.lru.Remove(element)
+		delete(m.entries, key)
+	}
+}
+
+func (m *memoryCache) Paths() map[string]string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	paths := make(map[string]string, len(m.entries))
+	for key, element := range m.entries {
+		paths[key] = element.Value.(*memoryCacheItem).entry.Path
+	}
+	return paths
+}
+
+func (m *memoryCache) Keys() []string {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	keys := make([]string, 0, len(m.entries))
+	for key := range m.entries {
+		keys = append(keys, key)
+	}
+	return keys
+}
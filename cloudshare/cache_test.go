@@ -0,0 +1,238 @@
+package cloudshare
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"net/http"
+	"sort"
+	"testing"
+	"time"
+)
+
+// newCatalogClient serves regions and policies, counting requests per path
+func newCatalogClient(t *testing.T, requests map[string]int) *Client {
+	return newLocalClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests[r.Method+" "+r.URL.Path]++
+		w.Write([]byte(fmt.Sprintf(`[{"name": "%s-%d"}]`, r.URL.Path, requests[r.Method+" "+r.URL.Path])))
+	})
+}
+
+func TestCacheFreshResponses(t *testing.T) {
+	requests := map[string]int{}
+	c := newCatalogClient(t, requests)
+	c.Cache = &ResponseCache{}
+
+	for i := 0; i < 3; i++ {
+		regions := []Region{}
+		require.NoError(t, c.GetRegions(&regions))
+		assert.Equal(t, "/api/v3/regions-1", regions[0].Name)
+	}
+	assert.Equal(t, 1, requests["GET /api/v3/regions"])
+
+	for i := 0; i < 2; i++ {
+		_, err := c.Request("GET", "envs", nil, nil)
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 2, requests["GET /api/v3/envs"], "only catalog endpoints are cached")
+}
+
+func TestCacheExpiration(t *testing.T) {
+	requests := map[string]int{}
+	c := newCatalogClient(t, requests)
+	c.Cache = &ResponseCache{TTLs: map[string]time.Duration{"regions": 20 * time.Millisecond}}
+
+	regions := []Region{}
+	require.NoError(t, c.GetRegions(&regions))
+	time.Sleep(30 * time.Millisecond)
+	require.NoError(t, c.GetRegions(&regions))
+	assert.Equal(t, "/api/v3/regions-2", regions[0].Name)
+}
+
+func TestCacheRevalidation(t *testing.T) {
+	requests := 0
+	c := newLocalClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.Header().Set("ETag", `"v1"`)
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		w.Write([]byte(`[{"name": "Miami"}]`))
+	})
+	c.Cache = &ResponseCache{TTLs: map[string]time.Duration{"regions": 0}}
+	var logged bytes.Buffer
+	c.Logger = slog.New(slog.NewJSONHandler(&logged, &slog.HandlerOptions{Level: slog.LevelWarn}))
+
+	for i := 0; i < 3; i++ {
+		regions := []Region{}
+		require.NoError(t, c.GetRegions(&regions))
+		assert.Equal(t, "Miami", regions[0].Name)
+	}
+	assert.Equal(t, 3, requests, "stale responses are revalidated")
+	assert.Empty(t, logged.String(), "revalidations aren't logged as failures")
+}
+
+func TestCacheInvalidation(t *testing.T) {
+	requests := map[string]int{}
+	c := newCatalogClient(t, requests)
+	c.Cache = &ResponseCache{}
+	projectID := ProjectID("PR1234")
+
+	policies := []Policy{}
+	require.NoError(t, c.GetPolicies(projectID, &policies))
+	regions := []Region{}
+	require.NoError(t, c.GetRegions(&regions))
+
+	_, err := c.Request("POST", "policies", nil, nil)
+	require.NoError(t, err)
+	require.NoError(t, c.GetPolicies(projectID, &policies))
+	require.NoError(t, c.GetRegions(&regions))
+	assert.Equal(t, 2, requests["GET /api/v3/projects/PR1234/policies"], "creating a policy invalidates policies")
+	assert.Equal(t, 1, requests["GET /api/v3/regions"])
+
+	c.Cache.Invalidate("regions")
+	require.NoError(t, c.GetRegions(&regions))
+	assert.Equal(t, 2, requests["GET /api/v3/regions"])
+
+	c.Cache.Invalidate("")
+	assert.Empty(t, c.Cache.store.Keys())
+}
+
+func TestCacheInvalidationDuringRequest(t *testing.T) {
+	requests := 0
+	var c *Client
+	c = newLocalClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		if requests == 1 {
+			// a policy is created while the first response is on its way
+			c.Cache.Invalidate("projects")
+		}
+		w.Write([]byte(fmt.Sprintf(`[{"name": "p%d"}]`, requests)))
+	})
+	c.Cache = &ResponseCache{}
+
+	policies := []Policy{}
+	require.NoError(t, c.GetPolicies("PR1234", &policies))
+	assert.Equal(t, "p1", policies[0].Name)
+	require.NoError(t, c.GetPolicies("PR1234", &policies))
+	assert.Equal(t, "p2", policies[0].Name, "the response that raced the invalidation isn't cached")
+	require.NoError(t, c.GetPolicies("PR1234", &policies))
+	assert.Equal(t, 2, requests)
+}
+
+// countingStore counts the entries read from its store
+type countingStore struct {
+	CacheStore
+	gets int
+}
+
+func (s *countingStore) Get(key string) (*CacheEntry, bool) {
+	s.gets++
+	return s.CacheStore.Get(key)
+}
+
+func TestCacheInvalidationDoesNotReadEntries(t *testing.T) {
+	dir := t.TempDir()
+	previous := DiskCache{Dir: dir}
+	previous.Set("GET regions", &CacheEntry{Path: "regions", Expires: time.Now().Add(time.Hour)})
+	previous.Set("GET templates", &CacheEntry{Path: "templates", Expires: time.Now().Add(time.Hour)})
+
+	store := &countingStore{CacheStore: DiskCache{Dir: dir}}
+	rc := &ResponseCache{Store: store}
+	rc.Invalidate("regions")
+	assert.Equal(t, 2, store.gets, "the entries kept from before are indexed once")
+	rc.Invalidate("templates")
+	rc.Invalidate("")
+	assert.Equal(t, 2, store.gets)
+	assert.Empty(t, store.Keys())
+}
+
+func TestCacheErrorsNotCached(t *testing.T) {
+	requests := 0
+	c := newLocalClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests++
+		w.WriteHeader(http.StatusInternalServerError)
+	})
+	c.Cache = &ResponseCache{}
+	regions := []Region{}
+	assert.Error(t, c.GetRegions(&regions))
+	assert.Error(t, c.GetRegions(&regions))
+	assert.Equal(t, 2, requests)
+}
+
+func TestCachePerAPIID(t *testing.T) {
+	requests := map[string]int{}
+	c := newCatalogClient(t, requests)
+	c.Cache = &ResponseCache{}
+	other := *c
+	other.APIID = "other_api_id"
+
+	regions := []Region{}
+	require.NoError(t, c.GetRegions(&regions))
+	require.NoError(t, other.GetRegions(&regions))
+	assert.Equal(t, 2, requests["GET /api/v3/regions"])
+}
+
+func TestCacheTTLPrefixes(t *testing.T) {
+	rc := &ResponseCache{TTLs: map[string]time.Duration{"projects": time.Minute, "projects/PR1/blueprints": time.Hour}}
+	ttl, ok := rc.cached("GET", "projects/PR1/blueprints/BP1")
+	assert.True(t, ok)
+	assert.Equal(t, time.Hour, ttl, "the longest prefix applies")
+	ttl, _ = rc.cached("GET", "/projects/PR2")
+	assert.Equal(t, time.Minute, ttl)
+	_, ok = rc.cached("GET", "projectsX")
+	assert.False(t, ok)
+	_, ok = rc.cached("PUT", "projects")
+	assert.False(t, ok)
+}
+
+func TestMemoryCacheLRU(t *testing.T) {
+	m := NewMemoryCache(2)
+	m.Set("a", &CacheEntry{Path: "a"})
+	m.Set("b", &CacheEntry{Path: "b"})
+	m.Get("a")
+	m.Set("c", &CacheEntry{Path: "c"})
+
+	keys := m.Keys()
+	sort.Strings(keys)
+	assert.Equal(t, []string{"a", "c"}, keys, "the least recently used entry is evicted")
+	m.Delete("a")
+	_, ok := m.Get("a")
+	assert.False(t, ok)
+}
+
+func TestDiskCache(t *testing.T) {
+	d := DiskCache{Dir: t.TempDir() + "/cache"}
+	_, ok := d.Get("GET regions")
+	assert.False(t, ok)
+
+	expires := time.Now().Add(time.Hour).Round(0)
+	entry := &CacheEntry{Path: "regions", StatusCode: 200, Headers: http.Header{"Etag": {`"v1"`}}, Body: []byte(`[]`), Expires: expires}
+	d.Set("GET regions", entry)
+	got, ok := d.Get("GET regions")
+	require.True(t, ok)
+	assert.True(t, expires.Equal(got.Expires))
+	got.Expires = expires
+	assert.Equal(t, entry, got)
+	assert.Equal(t, []string{"GET regions"}, d.Keys())
+
+	d.Delete("GET regions")
+	assert.Empty(t, d.Keys())
+}
+
+func TestDiskCacheClient(t *testing.T) {
+	requests := map[string]int{}
+	c := newCatalogClient(t, requests)
+	dir := t.TempDir()
+	c.Cache = &ResponseCache{Store: DiskCache{Dir: dir}}
+
+	regions := []Region{}
+	require.NoError(t, c.GetRegions(&regions))
+	// a new cache on the same directory, e.g. in the next run of a tool
+	c.Cache = &ResponseCache{Store: DiskCache{Dir: dir}}
+	require.NoError(t, c.GetRegions(&regions))
+	assert.Equal(t, 1, requests["GET /api/v3/regions"])
+}
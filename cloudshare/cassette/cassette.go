@@ -0,0 +1,320 @@
+/*
+Package cassette records HTTP interactions to JSON files ("cassettes") and replays them,
+so that tests of code using the CloudShare API can run offline and deterministically.
+
+When recording, requests are sent with Recorder.Transport and every interaction is kept, with the
+Authorization header and other credentials scrubbed, and the values of sensitive JSON fields such as
+passwords redacted from the bodies (see Recorder.RedactedFields). Stop writes the cassette. When replaying,
+requests are answered from the cassette by matching their method, path, query and body; the host
+and headers are ignored, so a cassette recorded against the live API replays against any base URL,
+and the signatures of replayed requests don't need to match. Identical requests are answered in
+the order they were recorded, e.g. to replay polling.
+
+Cassettes are indented JSON, which diffs well enough in reviews; YAML isn't supported, as it would
+add a dependency to the SDK for a format the standard library doesn't handle.
+
+Example:
+
+	recorder, err := cassette.New("testdata/cassettes/regions.json", cassette.ModeAuto)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer recorder.Stop()
+
+	client := &cloudshare.Client{APIKey: apiKey, APIID: apiID, HTTPClient: recorder.Client()}
+*/
+package cassette
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/cloudshare/go-sdk/cloudshare/internal/redact"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Mode selects whether a Recorder records or replays
+type Mode int
+
+const (
+	// ModeReplay answers requests from the cassette, and fails requests that weren't recorded
+	ModeReplay Mode = iota
+	// ModeRecord sends requests and records them, overwriting the cassette on Stop
+	ModeRecord
+	// ModeAuto replays the cassette if it exists, and records it otherwise
+	ModeAuto
+)
+
+func (m Mode) String() string {
+	switch m {
+	case ModeReplay:
+		return "replay"
+	case ModeRecord:
+		return "record"
+	case ModeAuto:
+		return "auto"
+	}
+	return "unknown"
+}
+
+// ErrNoInteraction is returned when replaying a request that wasn't recorded
+var ErrNoInteraction = errors.New("cassette: no recorded interaction")
+
+// Request is a recorded HTTP request
+type Request struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Headers http.Header `json:"headers,omitempty"`
+	Body    string      `json:"body,omitempty"`
+}
+
+// Response is a recorded HTTP response
+type Response struct {
+	StatusCode int         `json:"status"`
+	Headers    http.Header `json:"headers,omitempty"`
+	Body       string      `json:"body,omitempty"`
+}
+
+// Interaction is a recorded request and its response
+type Interaction struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Cassette is the content of a cassette file
+type Cassette struct {
+	Interactions []*Interaction `json:"interactions"`
+}
+
+// ScrubbedHeaders are the headers whose values are replaced by "REDACTED" in recorded interactions
+var ScrubbedHeaders = []string{"Authorization", "Cookie", "Set-Cookie", "Proxy-Authorization"}
+
+// Recorder is an http.RoundTripper that records or replays a cassette. It's safe for concurrent use,
+// but its fields must not be changed once it's in use.
+type Recorder struct {
+	// Transport sends requests when recording. Defaults to http.DefaultTransport.
+	Transport http.RoundTripper
+	// RedactedFields are the JSON field names (case insensitive) whose values are replaced by "REDACTED"
+	// in recorded bodies. Requests are matched to recorded ones after the same redaction when replaying.
+	// Defaults to the fields cloudshare.DefaultRedactedFields returns, i.e. the VM passwords and console
+	// tokens returned by the API, and API keys. Set it to an empty, non-nil slice to record bodies verbatim.
+	RedactedFields []string
+	// Scrub, if set, is called on every interaction before it's recorded, after ScrubbedHeaders
+	// and RedactedFields are redacted, e.g. to redact other secrets
+	Scrub func(interaction *Interaction)
+	// Match decides whether a recorded request answers a request. Defaults to DefaultMatch.
+	Match func(request *http.Request, body []byte, recorded Request) bool
+
+	path string
+	mode Mode
+
+	mu       sync.Mutex
+	cassette Cassette
+	used     []bool
+}
+
+// New returns a recorder of the cassette at path. In ModeAuto, the mode is resolved to ModeReplay
+// if the file exists, and to ModeRecord otherwise.
+func New(path string, mode Mode) (*Recorder, error) {
+	if mode == ModeAuto {
+		mode = ModeRecord
+		if _, err := os.Stat(path); err == nil {
+			mode = ModeReplay
+		}
+	}
+	r := &Recorder{path: path, mode: mode}
+	if mode == ModeReplay {
+		buffer, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("cassette: %w", err)
+		}
+		if err := json.Unmarshal(buffer, &r.cassette); err != nil {
+			return nil, fmt.Errorf("cassette: invalid cassette %s: %w", path, err)
+		}
+		r.used = make([]bool, len(r.cassette.Interactions))
+	}
+	return r, nil
+}
+
+// Mode returns whether the recorder records or replays
+func (r *Recorder) Mode() Mode {
+	return r.mode
+}
+
+// Client returns an http.Client using the recorder as its transport, e.g. for Client.HTTPClient
+func (r *Recorder) Client() *http.Client {
+	return &http.Client{Transport: r}
+}
+
+// Stop writes the cassette when recording. It does nothing when replaying.
+func (r *Recorder) Stop() error {
+	if r.mode != ModeRecord {
+		return nil
+	}
+	r.mu.Lock()
+	buffer, err := json.MarshalIndent(&r.cassette, "", "  ")
+	r.mu.Unlock()
+	if err != nil {
+		return fmt.Errorf("cassette: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(r.path), 0o755); err != nil {
+		return fmt.Errorf("cassette: %w", err)
+	}
+	if err := os.WriteFile(r.path, append(buffer, '\n'), 0o644); err != nil {
+		return fmt.Errorf("cassette: %w", err)
+	}
+	return nil
+}
+
+// RoundTrip records or replays a request
+func (r *Recorder) RoundTrip(request *http.Request) (*http.Response, error) {
+	var body []byte
+	if request.Body != nil {
+		var err error
+		body, err = io.ReadAll(request.Body)
+		request.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if r.mode == ModeReplay {
+		return r.replay(request, body)
+	}
+	return r.record(request, body)
+}
+
+// redact redacts the RedactedFields of a body
+func (r *Recorder) redact(body []byte) string {
+	fields := r.RedactedFields
+	if fields == nil {
+		fields = redact.DefaultFields
+	}
+	if len(fields) == 0 {
+		return string(body)
+	}
+	return redact.JSON(body, fields)
+}
+
+func (r *Recorder) replay(request *http.Request, body []byte) (*http.Response, error) {
+	match := r.Match
+	if match == nil {
+		match = DefaultMatch
+	}
+	body = []byte(r.redact(body))
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for i, interaction := range r.cassette.Interactions {
+		if r.used[i] || !match(request, body, interaction.Request) {
+			continue
+		}
+		r.used[i] = true
+		return newResponse(request, interaction.Response), nil
+	}
+	return nil, fmt.Errorf("%w for %s %s", ErrNoInteraction, request.Method, request.URL.RequestURI())
+}
+
+func (r *Recorder) record(request *http.Request, body []byte) (*http.Response, error) {
+	transport := r.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	sent := request.Clone(request.Context())
+	if request.Body != nil {
+		sent.Body = io.NopCloser(bytes.NewReader(body))
+	}
+	response, err := transport.RoundTrip(sent)
+	if err != nil {
+		return nil, err
+	}
+	defer response.Body.Close()
+	responseBody, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	interaction := &Interaction{
+		Request: Request{
+			Method:  request.Method,
+			URL:     request.URL.String(),
+			Headers: scrub(request.Header),
+			Body:    r.redact(body),
+		},
+		Response: Response{
+			StatusCode: response.StatusCode,
+			Headers:    scrub(response.Header),
+			Body:       r.redact(responseBody),
+		},
+	}
+	if r.Scrub != nil {
+		r.Scrub(interaction)
+	}
+	r.mu.Lock()
+	r.cassette.Interactions = append(r.cassette.Interactions, interaction)
+	r.mu.Unlock()
+
+	response.Body = io.NopCloser(bytes.NewReader(responseBody))
+	return response, nil
+}
+
+func scrub(header http.Header) http.Header {
+	header = header.Clone()
+	for _, key := range ScrubbedHeaders {
+		if _, ok := header[http.CanonicalHeaderKey(key)]; ok {
+			header.Set(key, "REDACTED")
+		}
+	}
+	return header
+}
+
+func newResponse(request *http.Request, recorded Response) *http.Response {
+	header := recorded.Headers.Clone()
+	if header == nil {
+		header = http.Header{}
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", recorded.StatusCode, http.StatusText(recorded.StatusCode)),
+		StatusCode:    recorded.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        header,
+		Body:          io.NopCloser(strings.NewReader(recorded.Body)),
+		ContentLength: int64(len(recorded.Body)),
+		Request:       request,
+	}
+}
+
+// DefaultMatch matches requests with the same method, path, query parameters (in any order)
+// and body, comparing JSON bodies by value
+func DefaultMatch(request *http.Request, body []byte, recorded Request) bool {
+	if request.Method != recorded.Method {
+		return false
+	}
+	u, err := url.Parse(recorded.URL)
+	if err != nil || u.Path != request.URL.Path {
+		return false
+	}
+	if !reflect.DeepEqual(u.Query(), request.URL.Query()) {
+		return false
+	}
+	return equalBodies(body, []byte(recorded.Body))
+}
+
+func equalBodies(a []byte, b []byte) bool {
+	if bytes.Equal(a, b) {
+		return true
+	}
+	var valueA, valueB interface{}
+	if json.Unmarshal(a, &valueA) != nil || json.Unmarshal(b, &valueB) != nil {
+		return false
+	}
+	return reflect.DeepEqual(valueA, valueB)
+}
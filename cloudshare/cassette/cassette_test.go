@@ -0,0 +1,148 @@
+package cassette
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newServer counts the requests to each path, and echoes the count and the request body
+func newServer(t *testing.T) *httptest.Server {
+	counts := map[string]int{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		counts[r.URL.Path]++
+		body, _ := io.ReadAll(r.Body)
+		w.Header().Set("Set-Cookie", "session=secret")
+		w.Header().Set("X-Count", strings.Repeat("*", counts[r.URL.Path]))
+		w.Write(append([]byte(r.URL.Path+" "), body...))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func get(t *testing.T, client *http.Client, rawURL string) string {
+	request, err := http.NewRequest("GET", rawURL, nil)
+	require.NoError(t, err)
+	request.Header.Set("Authorization", "cs_sha1 userapiid:id;timestamp:1;token:abc;hmac:0123")
+	response, err := client.Do(request)
+	require.NoError(t, err)
+	defer response.Body.Close()
+	body, err := io.ReadAll(response.Body)
+	require.NoError(t, err)
+	return response.Header.Get("X-Count") + " " + string(body)
+}
+
+func post(t *testing.T, client *http.Client, rawURL string, body string) (string, error) {
+	response, err := client.Post(rawURL, "application/json", strings.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	defer response.Body.Close()
+	ret, err := io.ReadAll(response.Body)
+	return string(ret), err
+}
+
+func TestRecordAndReplay(t *testing.T) {
+	server := newServer(t)
+	path := filepath.Join(t.TempDir(), "cassettes", "test.json")
+
+	recorder, err := New(path, ModeAuto)
+	require.NoError(t, err)
+	assert.Equal(t, ModeRecord, recorder.Mode())
+	client := recorder.Client()
+	assert.Equal(t, "* /api/v3/regions ", get(t, client, server.URL+"/api/v3/regions?a=1&b=2"))
+	assert.Equal(t, "** /api/v3/regions ", get(t, client, server.URL+"/api/v3/regions?a=1&b=2"))
+	body, err := post(t, client, server.URL+"/api/v3/policies", `{"name": "p", "minutes": 60}`)
+	require.NoError(t, err)
+	assert.Equal(t, `/api/v3/policies {"name": "p", "minutes": 60}`, body)
+	require.NoError(t, recorder.Stop())
+
+	recorded, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(recorded), "hmac", "credentials are scrubbed")
+	assert.NotContains(t, string(recorded), "secret")
+	assert.Contains(t, string(recorded), "REDACTED")
+
+	server.Close()
+	replayer, err := New(path, ModeAuto)
+	require.NoError(t, err)
+	assert.Equal(t, ModeReplay, replayer.Mode())
+	client = replayer.Client()
+	assert.Equal(t, "* /api/v3/regions ", get(t, client, "https://elsewhere.example.com/api/v3/regions?b=2&a=1"),
+		"the host and the order of query parameters don't matter")
+	assert.Equal(t, "** /api/v3/regions ", get(t, client, server.URL+"/api/v3/regions?a=1&b=2"),
+		"identical requests are replayed in order")
+	body, err = post(t, client, server.URL+"/api/v3/policies", `{"minutes":60,"name":"p"}`)
+	require.NoError(t, err)
+	assert.Equal(t, `/api/v3/policies {"name": "p", "minutes": 60}`, body, "JSON bodies are compared by value")
+
+	_, err = post(t, client, server.URL+"/api/v3/policies", `{"name": "other"}`)
+	assert.True(t, errors.Is(err, ErrNoInteraction))
+	_, err = client.Get(server.URL + "/api/v3/regions?a=1&b=2")
+	assert.True(t, errors.Is(err, ErrNoInteraction), "every interaction is replayed once")
+	require.NoError(t, replayer.Stop())
+}
+
+func TestScrub(t *testing.T) {
+	server := newServer(t)
+	path := filepath.Join(t.TempDir(), "test.json")
+	recorder, err := New(path, ModeRecord)
+	require.NoError(t, err)
+	recorder.Scrub = func(interaction *Interaction) {
+		interaction.Request.Body = strings.ReplaceAll(interaction.Request.Body, "hunter2", "REDACTED")
+		interaction.Response.Body = strings.ReplaceAll(interaction.Response.Body, "hunter2", "REDACTED")
+	}
+	body, err := post(t, recorder.Client(), server.URL+"/login", `{"password": "hunter2"}`)
+	require.NoError(t, err)
+	assert.Equal(t, `/login {"password": "hunter2"}`, body, "the caller sees the actual response")
+	require.NoError(t, recorder.Stop())
+
+	recorded, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(recorded), "hunter2")
+}
+
+func TestRedactedFields(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"vms": [{"name": "vm1", "password": "hunter2", "consoleToken": "abc"}]}`))
+	}))
+	t.Cleanup(server.Close)
+	path := filepath.Join(t.TempDir(), "test.json")
+
+	recorder, err := New(path, ModeRecord)
+	require.NoError(t, err)
+	body, err := post(t, recorder.Client(), server.URL+"/login", `{"user": "u", "Password": "hunter2"}`)
+	require.NoError(t, err)
+	assert.Contains(t, body, "hunter2", "the caller sees the actual response")
+	require.NoError(t, recorder.Stop())
+
+	recorded, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.NotContains(t, string(recorded), "hunter2", "passwords are redacted by default")
+	assert.NotContains(t, string(recorded), `\"abc\"`)
+
+	replayer, err := New(path, ModeReplay)
+	require.NoError(t, err)
+	body, err = post(t, replayer.Client(), server.URL+"/login", `{"Password": "other", "user": "u"}`)
+	require.NoError(t, err, "requests are matched after redaction")
+	assert.JSONEq(t, `{"vms": [{"name": "vm1", "password": "REDACTED", "consoleToken": "REDACTED"}]}`, body)
+
+	verbatim, err := New(filepath.Join(t.TempDir(), "verbatim.json"), ModeRecord)
+	require.NoError(t, err)
+	verbatim.RedactedFields = []string{}
+	_, err = post(t, verbatim.Client(), server.URL+"/login", `{"password": "hunter2"}`)
+	require.NoError(t, err)
+	assert.Contains(t, verbatim.cassette.Interactions[0].Request.Body, "hunter2")
+}
+
+func TestReplayMissingCassette(t *testing.T) {
+	_, err := New(filepath.Join(t.TempDir(), "missing.json"), ModeReplay)
+	assert.True(t, errors.Is(err, os.ErrNotExist))
+}
@@ -0,0 +1,246 @@
+package cloudshare
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrCircuitOpen is returned, wrapped in an *APIError, for requests rejected by an open CircuitBreaker
+var ErrCircuitOpen = errors.New("cloudshare: circuit open")
+
+// CircuitState is the state of a host's circuit
+type CircuitState int
+
+const (
+	// CircuitClosed lets requests through
+	CircuitClosed CircuitState = iota
+	// CircuitOpen rejects requests with ErrCircuitOpen
+	CircuitOpen
+	// CircuitHalfOpen lets a few trial requests through, to find out whether the host has recovered
+	CircuitHalfOpen
+)
+
+func (s CircuitState) String() string {
+	switch s {
+	case CircuitClosed:
+		return "closed"
+	case CircuitOpen:
+		return "open"
+	case CircuitHalfOpen:
+		return "half-open"
+	}
+	return "unknown"
+}
+
+// BreakerSettings are the thresholds of a circuit
+type BreakerSettings struct {
+	// FailureThreshold is the number of consecutive failures that opens the circuit. Defaults to 5.
+	FailureThreshold int
+	// OpenTimeout is how long the circuit stays open before letting trial requests through. Defaults to 30s.
+	OpenTimeout time.Duration
+	// HalfOpenRequests is the number of trial requests that must succeed to close the circuit. Defaults to 1.
+	HalfOpenRequests int
+}
+
+func (s BreakerSettings) withDefaults() BreakerSettings {
+	if s.FailureThreshold <= 0 {
+		s.FailureThreshold = 5
+	}
+	if s.OpenTimeout <= 0 {
+		s.OpenTimeout = 30 * time.Second
+	}
+	if s.HalfOpenRequests <= 0 {
+		s.HalfOpenRequests = 1
+	}
+	return s
+}
+
+/*
+CircuitBreaker stops clients from sending requests to an API host that keeps failing.
+Each host has its own circuit. After FailureThreshold consecutive failures, the circuit opens,
+and requests fail immediately with an *APIError wrapping ErrCircuitOpen. After OpenTimeout,
+the circuit is half-open: HalfOpenRequests trial requests are let through, and the circuit closes
+if they all succeed, or opens again on the first failure.
+
+Requests that fail because their own context is done don't count. Retried requests count every attempt.
+
+A CircuitBreaker is safe for concurrent use, and must not be copied or modified once in use.
+Share one between clients to share the circuits' state.
+
+Example:
+
+	breaker := &cloudshare.CircuitBreaker{
+		Settings: cloudshare.BreakerSettings{FailureThreshold: 10, OpenTimeout: time.Minute},
+		OnStateChange: func(host string, from cloudshare.CircuitState, to cloudshare.CircuitState) {
+			log.Printf("circuit of %s is %s", host, to)
+		},
+	}
+	client, err := cloudshare.NewClient(cloudshare.WithCircuitBreaker(breaker))
+*/
+type CircuitBreaker struct {
+	// Settings apply to hosts that aren't in HostSettings
+	Settings BreakerSettings
+	// HostSettings overrides Settings for some hosts, e.g. "use.cloudshare.com"
+	HostSettings map[string]BreakerSettings
+	// IsFailure decides whether a request's outcome counts as a failure. Defaults to DefaultBreakerFailure.
+	IsFailure func(res *APIResponse, err error) bool
+	// OnStateChange, if set, is called whenever a host's circuit changes state, e.g. for alerting.
+	// It's called synchronously by the request that caused the change.
+	OnStateChange func(host string, from CircuitState, to CircuitState)
+
+	mu       sync.Mutex
+	circuits map[string]*circuit
+}
+
+// DefaultBreakerFailure counts network failures and 5xx responses as failures
+func DefaultBreakerFailure(res *APIResponse, err error) bool {
+	if err == nil {
+		return false
+	}
+	return res == nil || res.StatusCode/100 == 5
+}
+
+type circuit struct {
+	state CircuitState
+	// generation changes with every state change, so that late outcomes of requests let through
+	// in a previous state are ignored
+	generation int
+	failures   int
+	trials     int
+	successes  int
+	openedAt   time.Time
+}
+
+// State returns the state of a host's circuit
+func (b *CircuitBreaker) State(host string) CircuitState {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c := b.circuits[host]
+	if c == nil {
+		return CircuitClosed
+	}
+	if c.state == CircuitOpen && time.Since(c.openedAt) >= b.settings(host).OpenTimeout {
+		return CircuitHalfOpen
+	}
+	return c.state
+}
+
+func (b *CircuitBreaker) settings(host string) BreakerSettings {
+	if s, ok := b.HostSettings[host]; ok {
+		return s.withDefaults()
+	}
+	return b.Settings.withDefaults()
+}
+
+func (b *CircuitBreaker) circuit(host string) *circuit {
+	if b.circuits == nil {
+		b.circuits = map[string]*circuit{}
+	}
+	c := b.circuits[host]
+	if c == nil {
+		c = &circuit{}
+		b.circuits[host] = c
+	}
+	return c
+}
+
+// transition changes the circuit's state, returning a function that notifies OnStateChange
+func (b *CircuitBreaker) transition(host string, c *circuit, to CircuitState, now time.Time) func() {
+	from := c.state
+	c.state = to
+	c.generation++
+	c.failures, c.trials, c.successes = 0, 0, 0
+	if to == CircuitOpen {
+		c.openedAt = now
+	}
+	return func() {
+		if b.OnStateChange != nil {
+			b.OnStateChange(host, from, to)
+		}
+	}
+}
+
+// allow checks whether a request to host may be sent. If so, it returns a function to report its outcome.
+// A nil breaker allows everything.
+func (b *CircuitBreaker) allow(host string) (func(ctx context.Context, res *APIResponse, err error), error) {
+	if b == nil {
+		return func(context.Context, *APIResponse, error) {}, nil
+	}
+	notify := func() {}
+	defer func() { notify() }()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	settings := b.settings(host)
+	c := b.circuit(host)
+	now := time.Now()
+
+	if c.state == CircuitOpen {
+		if now.Sub(c.openedAt) < settings.OpenTimeout {
+			return nil, ErrCircuitOpen
+		}
+		notify = b.transition(host, c, CircuitHalfOpen, now)
+	}
+	if c.state == CircuitHalfOpen {
+		if c.trials >= settings.HalfOpenRequests {
+			return nil, ErrCircuitOpen
+		}
+		c.trials++
+	}
+
+	generation := c.generation
+	return func(ctx context.Context, res *APIResponse, err error) {
+		b.record(host, generation, ctx.Err() == nil, b.isFailure(res, err))
+	}, nil
+}
+
+// record updates a circuit with the outcome of a request let through in the given generation.
+// Outcomes that aren't counted only free their trial slot.
+func (b *CircuitBreaker) record(host string, generation int, counted bool, failed bool) {
+	notify := func() {}
+	defer func() { notify() }()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	settings := b.settings(host)
+	c := b.circuit(host)
+	if c.generation != generation {
+		return
+	}
+	now := time.Now()
+
+	switch c.state {
+	case CircuitClosed:
+		switch {
+		case !counted:
+		case failed:
+			c.failures++
+			if c.failures >= settings.FailureThreshold {
+				notify = b.transition(host, c, CircuitOpen, now)
+			}
+		default:
+			c.failures = 0
+		}
+	case CircuitHalfOpen:
+		switch {
+		case !counted:
+			c.trials--
+		case failed:
+			notify = b.transition(host, c, CircuitOpen, now)
+		default:
+			c.successes++
+			if c.successes >= settings.HalfOpenRequests {
+				notify = b.transition(host, c, CircuitClosed, now)
+			}
+		}
+	}
+}
+
+func (b *CircuitBreaker) isFailure(res *APIResponse, err error) bool {
+	if b.IsFailure != nil {
+		return b.IsFailure(res, err)
+	}
+	return DefaultBreakerFailure(res, err)
+}
@@ -0,0 +1,156 @@
+package cloudshare
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type stateChange struct {
+	from CircuitState
+	to   CircuitState
+}
+
+// newFlakyClient fails requests with 503 while failing is set
+func newFlakyClient(t *testing.T, failing *atomic.Bool, requests *atomic.Int32) *Client {
+	return newLocalClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		if failing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`[]`))
+	})
+}
+
+func TestCircuitBreaker(t *testing.T) {
+	var failing atomic.Bool
+	var requests atomic.Int32
+	failing.Store(true)
+	c := newFlakyClient(t, &failing, &requests)
+
+	var mu sync.Mutex
+	changes := []stateChange{}
+	c.CircuitBreaker = &CircuitBreaker{
+		Settings: BreakerSettings{FailureThreshold: 3, OpenTimeout: 50 * time.Millisecond},
+		OnStateChange: func(host string, from CircuitState, to CircuitState) {
+			assert.Equal(t, c.host(), host)
+			mu.Lock()
+			defer mu.Unlock()
+			changes = append(changes, stateChange{from, to})
+		},
+	}
+
+	for i := 0; i < 3; i++ {
+		_, err := c.Request("GET", "projects", nil, nil)
+		assert.True(t, errors.Is(err, ErrServer))
+	}
+	assert.Equal(t, CircuitOpen, c.CircuitBreaker.State(c.host()))
+
+	_, err := c.Request("GET", "projects", nil, nil)
+	assert.True(t, errors.Is(err, ErrCircuitOpen))
+	var apiErr *APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, "GET", apiErr.Method)
+	assert.Equal(t, int32(3), requests.Load(), "open circuits don't send requests")
+
+	time.Sleep(60 * time.Millisecond)
+	assert.Equal(t, CircuitHalfOpen, c.CircuitBreaker.State(c.host()))
+	_, err = c.Request("GET", "projects", nil, nil)
+	assert.True(t, errors.Is(err, ErrServer), "a failed trial reopens the circuit")
+	_, err = c.Request("GET", "projects", nil, nil)
+	assert.True(t, errors.Is(err, ErrCircuitOpen))
+
+	time.Sleep(60 * time.Millisecond)
+	failing.Store(false)
+	_, err = c.Request("GET", "projects", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, CircuitClosed, c.CircuitBreaker.State(c.host()))
+
+	assert.Equal(t, []stateChange{
+		{CircuitClosed, CircuitOpen},
+		{CircuitOpen, CircuitHalfOpen},
+		{CircuitHalfOpen, CircuitOpen},
+		{CircuitOpen, CircuitHalfOpen},
+		{CircuitHalfOpen, CircuitClosed},
+	}, changes)
+}
+
+func TestCircuitBreakerSuccessResetsFailures(t *testing.T) {
+	var failing atomic.Bool
+	var requests atomic.Int32
+	c := newFlakyClient(t, &failing, &requests)
+	c.CircuitBreaker = &CircuitBreaker{Settings: BreakerSettings{FailureThreshold: 2}}
+
+	for i := 0; i < 5; i++ {
+		failing.Store(true)
+		c.Request("GET", "projects", nil, nil)
+		failing.Store(false)
+		c.Request("GET", "projects", nil, nil)
+	}
+	assert.Equal(t, CircuitClosed, c.CircuitBreaker.State(c.host()))
+}
+
+func TestCircuitBreakerIgnoresClientErrors(t *testing.T) {
+	c := newLocalClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+	})
+	c.CircuitBreaker = &CircuitBreaker{Settings: BreakerSettings{FailureThreshold: 1}}
+	for i := 0; i < 3; i++ {
+		_, err := c.Request("GET", "envs/EN123", nil, nil)
+		assert.True(t, errors.Is(err, ErrNotFound))
+	}
+	assert.Equal(t, CircuitClosed, c.CircuitBreaker.State(c.host()))
+}
+
+func TestCircuitBreakerIgnoresCancellation(t *testing.T) {
+	c := newLocalClient(t, func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	c.CircuitBreaker = &CircuitBreaker{Settings: BreakerSettings{FailureThreshold: 1}}
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	_, err := c.RequestWithContext(ctx, "GET", "projects", nil, nil)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Equal(t, CircuitClosed, c.CircuitBreaker.State(c.host()))
+}
+
+func TestCircuitBreakerHostSettings(t *testing.T) {
+	b := &CircuitBreaker{
+		Settings:     BreakerSettings{FailureThreshold: 1},
+		HostSettings: map[string]BreakerSettings{"tolerant": {FailureThreshold: 3}},
+	}
+	for _, host := range []string{"strict", "tolerant"} {
+		report, err := b.allow(host)
+		require.NoError(t, err)
+		report(context.Background(), nil, errors.New("connection refused"))
+	}
+	assert.Equal(t, CircuitOpen, b.State("strict"))
+	assert.Equal(t, CircuitClosed, b.State("tolerant"))
+	assert.Equal(t, CircuitClosed, b.State("unknown"))
+}
+
+func TestCircuitBreakerHalfOpenTrials(t *testing.T) {
+	b := &CircuitBreaker{Settings: BreakerSettings{FailureThreshold: 1, OpenTimeout: time.Millisecond, HalfOpenRequests: 2}}
+	report, _ := b.allow("host")
+	report(context.Background(), nil, errors.New("timeout"))
+	time.Sleep(2 * time.Millisecond)
+
+	first, err := b.allow("host")
+	require.NoError(t, err)
+	second, err := b.allow("host")
+	require.NoError(t, err)
+	_, err = b.allow("host")
+	assert.Equal(t, ErrCircuitOpen, err, "only HalfOpenRequests trials are let through")
+
+	first(context.Background(), &APIResponse{StatusCode: 200}, nil)
+	assert.Equal(t, CircuitHalfOpen, b.State("host"))
+	second(context.Background(), &APIResponse{StatusCode: 200}, nil)
+	assert.Equal(t, CircuitClosed, b.State("host"))
+}
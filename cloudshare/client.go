@@ -1,51 +1,159 @@
 package cloudshare
 
 import (
-	"crypto/tls"
+	"context"
 	"encoding/json"
+	"fmt"
+	"io"
 	"io/ioutil"
+	"log/slog"
 	"net/http"
 	"net/url"
-	"os"
+	"regexp"
 	"strings"
+	"time"
 )
 
 // Client holds the API credentials can be found in your User Details page.
 // APIKey & APIID are mandatory, and you can get your keys on the user details page.
+// Credentials is optional, and provides the keys when APIKey & APIID aren't set (see Credentials).
 // Tags is optional, and defaults to "go_sdk". It's for internal analytics, so feel free to ignore it.
+// BaseURL is optional, and defaults to DefaultBaseURL. Use it to target a gateway under a sub-path,
+// a plain-HTTP stand-in or another API version, e.g. "http://localhost:8080/cloudshare/api/v3/".
+// APIHost is a shortcut that only replaces the host of the default base URL. BaseURL takes precedence.
+// Retry is optional. When nil, failed requests are not retried.
+// HTTPClient is optional. When nil, a shared client with connection pooling is used.
+// Use NewHTTPClient to configure a proxy, custom root CAs, client certificates or timeouts,
+// or set your own http.Client to plug in a custom RoundTripper.
+// CircuitBreaker is optional, and fails requests fast while the API host keeps failing (see CircuitBreaker).
+// Cache is optional, and caches the responses of catalog endpoints (see ResponseCache).
+// RateLimiter is optional, and throttles requests, e.g. to share a budget between clients (see RateLimiter).
+// Middleware is optional, and wraps every HTTP request sent by the client (see Middleware).
+// Signer is optional, and defaults to the cs_sha1 scheme (see SHA1Signer).
+// Logger is optional. When set, every HTTP request is logged at debug level (warn level on failure),
+// with the Authorization header secrets and sensitive JSON fields redacted.
+// LogBodies adds the request and response bodies to the log.
+// RedactedFields are the JSON field names redacted from logged bodies. Defaults to DefaultRedactedFields().
+// ValidateTransitions makes EnvironmentSuspend, EnvironmentResume, EnvironmentExtend and EnvironmentPostpone
+// fetch the environment's status first, and fail with a *TransitionError if the action doesn't apply to it.
+// It's off by default because it costs an extra request per action, the status can still change between
+// the check and the action, and the API rejects invalid actions anyway, only with a less specific error.
+//
+// A Client is safe for concurrent use, as long as its fields aren't changed once it's in use.
+// The fields stay exported, rather than hidden behind accessors, so that clients built as struct literals
+// keep compiling; immutability is a convention the SDK itself follows, not something it enforces.
+// NewClient validates the configuration up front; use With to derive a client with different options
+// (the derived client shares the pointer fields of the original, see With).
 type Client struct {
-	APIKey  string
-	APIID   string
-	Tags    string
-	APIHost string
+	APIKey         string
+	APIID          string
+	Credentials    CredentialsProvider
+	Tags           string
+	BaseURL        string
+	APIHost        string
+	Retry          *RetryPolicy
+	RateLimiter    *RateLimiter
+	CircuitBreaker *CircuitBreaker
+	Cache          *ResponseCache
+	HTTPClient     *http.Client
+	Middleware     []Middleware
+	Signer         Signer
+	Logger         *slog.Logger
+	LogBodies      bool
+	RedactedFields []string
+
+	ValidateTransitions bool
+
+	// credentialsSet is set by the credential options while With applies them
+	credentialsSet bool
 }
 
-func (c *Client) buildURL(path string, params *url.Values) *url.URL {
+// DefaultBaseURL is the base URL of the CloudShare REST API
+const DefaultBaseURL = "https://use.cloudshare.com/api/v3/"
+
+// ParseBaseURL parses and validates an API base URL.
+// The URL must be absolute, use the http or https scheme, and have no query or fragment.
+// A trailing slash is added to the path if missing.
+func ParseBaseURL(rawURL string) (*url.URL, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid base URL %q: %s", rawURL, err)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" {
+		return nil, fmt.Errorf("invalid base URL %q: scheme must be http or https", rawURL)
+	}
+	if u.Host == "" {
+		return nil, fmt.Errorf("invalid base URL %q: host is missing", rawURL)
+	}
+	if u.User != nil || u.RawQuery != "" || u.Fragment != "" {
+		return nil, fmt.Errorf("invalid base URL %q: credentials, query and fragment are not allowed", rawURL)
+	}
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	u.RawPath = ""
+	return u, nil
+}
 
+func (c *Client) tags() string {
+	if c.Tags == "" {
+		return "go_sdk"
+	}
+	return c.Tags
+}
+
+// host returns the host of the base URL, or "" if it's invalid
+func (c *Client) host() string {
+	u, err := c.baseURL()
+	if err != nil {
+		return ""
+	}
+	return u.Host
+}
+
+func (c *Client) baseURL() (*url.URL, error) {
+	if c.BaseURL != "" {
+		return ParseBaseURL(c.BaseURL)
+	}
 	host := c.APIHost
 	if host == "" {
 		host = "use.cloudshare.com"
-
 	}
-
-	u := &url.URL{
+	return &url.URL{
 		Scheme: "https",
 		Host:   host,
-		Path:   "/api/v3/" + strings.TrimLeft(path, "/"),
+		Path:   "/api/v3/",
+	}, nil
+}
+
+// webRoot returns the root URL of the web UI served next to the API,
+// i.e. the base URL without its "api/v3/" suffix
+func (c *Client) webRoot() (*url.URL, error) {
+	u, err := c.baseURL()
+	if err != nil {
+		return nil, err
+	}
+	u.Path = apiVersionSuffix.ReplaceAllString(u.Path, "")
+	if !strings.HasSuffix(u.Path, "/") {
+		u.Path += "/"
+	}
+	return u, nil
+}
+
+var apiVersionSuffix = regexp.MustCompile(`api/v\d+/$`)
+
+func (c *Client) buildURL(path string, params *url.Values) (*url.URL, error) {
+	u, err := c.baseURL()
+	if err != nil {
+		return nil, err
 	}
+	u.Path += strings.TrimLeft(path, "/")
 
 	if params != nil {
 		u.RawQuery = url.Values.Encode(*params)
 	}
 
-	return u
-}
-
-// APIError is returned by client.Request in case of a failure.
-type APIError struct {
-	Code       string `json:"code"`
-	Message    string `json:"message"`
-	InnerError error
+	return u, nil
 }
 
 // APIResponse is returned by client.Request in case of success.
@@ -57,14 +165,6 @@ type APIResponse struct {
 	Headers    http.Header
 }
 
-func (e APIError) Error() string {
-	s := e.Message
-	if e.InnerError != nil {
-		s += "\n" + e.InnerError.Error()
-	}
-	return s
-}
-
 /*
 
 Request invokes any API call
@@ -81,73 +181,201 @@ Example:
 		content: optional JSON body
 */
 func (c *Client) Request(method string, path string, queryParams *url.Values, content *string) (*APIResponse, error) {
-	client := http.Client{}
-	if os.Getenv("DEBUG") == "true" {
-		client.Transport = &http.Transport{
-			TLSClientConfig: &tls.Config{InsecureSkipVerify: true},
+	return c.RequestWithContext(context.Background(), method, path, queryParams, content)
+}
+
+// RequestWithContext is like Request, but the HTTP request is bound to ctx.
+// Cancelling ctx (or reaching its deadline) aborts the call, including while
+// the response body is being read.
+func (c *Client) RequestWithContext(ctx context.Context, method string, path string, queryParams *url.Values, content *string) (*APIResponse, error) {
+	method = strings.ToUpper(method)
+	client := c.httpClient()
+
+	if queryParams == nil {
+		queryParams = &url.Values{}
+	}
+	// queryParams.Set("apiTags", c.tags())
+
+	url, err := c.buildURL(path, queryParams)
+	if err != nil {
+		return nil, &APIError{
+			Message:    "Invalid API URL",
+			InnerError: err,
+			Method:     method,
+			Path:       path,
 		}
 	}
 
-	if c.Tags == "" {
-		c.Tags = "go_sdk"
+	creds, err := c.credentials(ctx)
+	if err != nil {
+		return nil, &APIError{
+			Message:    "Failed to get API credentials",
+			InnerError: err,
+			Method:     method,
+			Path:       path,
+		}
 	}
 
-	if queryParams == nil {
-		queryParams = &url.Values{}
+	send := func(header http.Header) (*APIResponse, error) {
+		return c.sendWithRetries(ctx, client, creds, method, path, url, content, header)
+	}
+	if ttl, ok := c.Cache.cached(method, path); ok {
+		// responses may differ between users, so they're cached per API ID
+		return c.Cache.do(method+" "+url.String()+" "+creds.APIID, path, ttl, send)
 	}
-	// queryParams.Set("apiTags", c.Tags)
+	res, err := send(nil)
+	c.Cache.invalidateAfter(method, path)
+	return res, err
+}
 
-	url := c.buildURL(path, queryParams)
+// sendWithRetries sends a request, with the extra header, until it succeeds or may no longer be retried
+func (c *Client) sendWithRetries(ctx context.Context, client *http.Client, creds Credentials, method string, path string, url *url.URL, content *string, header http.Header) (*APIResponse, error) {
+	maxAttempts := c.Retry.attempts(method)
+	host := c.host()
+	offset := c.clockOffset()
+	skewRetried := false
+	for attempt := 1; ; attempt++ {
+		signingOffset := time.Duration(offset.Load())
+		signingCtx := context.WithValue(ctx, signingOffsetKey{}, signingOffset)
+		report, err := c.CircuitBreaker.allow(host)
+		if err != nil {
+			return nil, &APIError{
+				Message:    "Circuit open for " + host,
+				InnerError: err,
+				Method:     method,
+				Path:       path,
+			}
+		}
+		done, err := c.RateLimiter.wait(ctx, method)
+		if err != nil {
+			report(ctx, nil, err)
+			return nil, &APIError{
+				Message:    "Aborted while waiting for the rate limiter",
+				InnerError: err,
+				Method:     method,
+				Path:       path,
+			}
+		}
+		res, err := c.doRequest(signingCtx, client, creds, method, path, url, attempt, content, header)
+		done(res, err)
+		report(ctx, res, err)
+		if measured, ok := detectClockSkew(signingOffset, res, err); ok && !skewRetried {
+			// re-sign with the server's time, once
+			offset.Store(int64(measured))
+			skewRetried = true
+			maxAttempts++
+			continue
+		}
+		if attempt >= maxAttempts || !c.Retry.shouldRetry(ctx, res, err) {
+			return res, err
+		}
+		if !sleepContext(ctx, c.Retry.delay(attempt, res)) {
+			return res, err
+		}
+	}
+}
 
-	// fmt.Printf("url: %s\n", url)
+// doRequest signs a single HTTP request and passes it through the middleware chain.
+// Each call signs the request anew, so it's safe to call it again when retrying.
+func (c *Client) doRequest(ctx context.Context, client *http.Client, creds Credentials, method string, path string, u *url.URL, attempt int, content *string, header http.Header) (*APIResponse, error) {
+	url := *u
 
 	headers := &http.Header{}
 	headers.Set("Content-Type", "application/json")
 	headers.Set("Accept", "application/json")
+	for key, values := range header {
+		(*headers)[key] = append([]string(nil), values...)
+	}
 
-	token := authToken(c.APIKey, c.APIID, url.String())
-	headers.Set("Authorization", "cs_sha1 "+token)
-
-	request := &http.Request{
+	request := (&http.Request{
 		Method: method,
-		URL:    url,
+		URL:    &url,
 		Header: *headers,
-	}
+	}).WithContext(ctx)
 
 	if content != nil {
 		bodyReader := strings.NewReader(*content)
 		request.Body = ioutil.NopCloser(bodyReader)
+		request.GetBody = func() (io.ReadCloser, error) {
+			return ioutil.NopCloser(strings.NewReader(*content)), nil
+		}
 
 		// TODO: Test this with Unicode
 		request.ContentLength = int64(len(*content))
-		// fmt.Printf("Request body: %s\n", *content)
 	}
 
+	if err := c.signer().Sign(request, creds); err != nil {
+		return nil, &APIError{
+			Message:    "Failed to sign request",
+			InnerError: err,
+			Method:     method,
+			Path:       path,
+		}
+	}
+
+	call := &Call{
+		Path:    strings.TrimLeft(path, "/"),
+		Attempt: attempt,
+		Request: request,
+		Start:   time.Now(),
+	}
+	return c.chain(sendHandler(client, c.Retry))(call)
+}
+
+// sendHandler returns the innermost Handler of the middleware chain, which
+// performs the HTTP round trip and reads the response. Failed responses are
+// marked retryable according to policy.
+func sendHandler(client *http.Client, policy *RetryPolicy) Handler {
+	return func(call *Call) (*APIResponse, error) {
+		return send(client, call, policy)
+	}
+}
+
+func send(client *http.Client, call *Call, policy *RetryPolicy) (*APIResponse, error) {
+	request := call.Request
 	response, err := client.Do(request)
 
 	if err != nil {
-		// fmt.Println(err)
-		return nil, APIError{InnerError: err}
+		return nil, &APIError{
+			Method:     request.Method,
+			Path:       call.Path,
+			InnerError: err,
+			Retryable:  request.Context().Err() == nil,
+		}
 	}
+	defer response.Body.Close()
 
 	body, err := ioutil.ReadAll(response.Body)
 	if response.StatusCode/100 != 2 {
 		if err != nil {
-			return nil, APIError{
+			return nil, &APIError{
 				Code:       "unknown error",
 				Message:    "failed to parse http response body",
 				InnerError: err,
+				StatusCode: response.StatusCode,
+				Method:     request.Method,
+				Path:       call.Path,
+				Retryable:  request.Context().Err() == nil,
 			}
 		}
-		var ret APIError
-		json.Unmarshal(body, &ret)
-		return &APIResponse{StatusCode: response.StatusCode, Body: body, Headers: response.Header}, &ret
+		ret := &APIError{}
+		json.Unmarshal(body, ret)
+		ret.StatusCode = response.StatusCode
+		ret.Method = request.Method
+		ret.Path = call.Path
+		ret.Body = body
+		ret.Retryable = policy.retryableStatus(response.StatusCode)
+		return &APIResponse{StatusCode: response.StatusCode, Body: body, Headers: response.Header}, ret
 	}
 
 	if err != nil {
-		return nil, APIError{
+		return nil, &APIError{
 			Message:    "Unable to read HTTP response body",
 			InnerError: err,
+			StatusCode: response.StatusCode,
+			Method:     request.Method,
+			Path:       call.Path,
+			Retryable:  request.Context().Err() == nil,
 		}
 	}
 
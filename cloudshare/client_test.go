@@ -1,10 +1,15 @@
 package cloudshare
 
 import (
+	"context"
 	"encoding/json"
+	"github.com/cloudshare/go-sdk/cloudshare/cassette"
 	"github.com/stretchr/testify/require"
+	"net/http"
+	"net/http/httptest"
 	"net/url"
 	"os"
+	"path/filepath"
 	"strings"
 	"testing"
 	"time"
@@ -33,17 +38,56 @@ func getClient() *Client {
 	}
 }
 
+func buildURLString(t *testing.T, c *Client, path string, params *url.Values) string {
+	u, err := c.buildURL(path, params)
+	require.NoError(t, err)
+	return u.String()
+}
+
 func TestBuildURL(t *testing.T) {
 	c := getClient()
 	require.Equal(t, "https://"+APIHost+"/api/v3/projects",
-		c.buildURL("projects", nil).String(), "failed to build url")
+		buildURLString(t, c, "projects", nil), "failed to build url")
 
 	require.Equal(t, "https://"+APIHost+"/api/v3/projects/",
-		c.buildURL("/projects/", nil).String(), "failed to build url")
+		buildURLString(t, c, "/projects/", nil), "failed to build url")
 
 	params := &url.Values{}
 	params.Set("key", "value_with/_in_it")
-	require.Equal(t, "https://"+APIHost+"/api/v3/path?key=value_with%2F_in_it", c.buildURL("path", params).String(), "url param encoding failed")
+	require.Equal(t, "https://"+APIHost+"/api/v3/path?key=value_with%2F_in_it", buildURLString(t, c, "path", params), "url param encoding failed")
+}
+
+func TestBuildURLWithBaseURL(t *testing.T) {
+	c := &Client{}
+	require.Equal(t, "https://use.cloudshare.com/api/v3/projects", buildURLString(t, c, "projects", nil))
+
+	c.BaseURL = "http://localhost:8080/cloudshare/api/v4"
+	c.APIHost = "ignored.example.com"
+	require.Equal(t, "http://localhost:8080/cloudshare/api/v4/projects", buildURLString(t, c, "/projects", nil))
+
+	envURL, err := c.EnvIDToURL("ENabc")
+	require.NoError(t, err)
+	require.Equal(t, "http://localhost:8080/cloudshare/Ent/Environment.mvc/View/abc", envURL)
+
+	for _, invalid := range []string{"localhost:8080", "ftp://host/api/v3/", "https:///api/v3/", "https://host/api/v3/?x=1", "://"} {
+		c.BaseURL = invalid
+		_, err := c.buildURL("projects", nil)
+		require.Error(t, err, invalid)
+		_, err = c.Request("GET", "projects", nil, nil)
+		require.Error(t, err, invalid)
+	}
+}
+
+func TestPlainHTTPBaseURL(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/prefix/api/v3/regions", r.URL.Path)
+		w.Write([]byte(`[]`))
+	}))
+	defer server.Close()
+
+	c := &Client{BaseURL: server.URL + "/prefix/api/v3/"}
+	regions := []Region{}
+	require.Nil(t, c.GetRegions(&regions))
 }
 
 type PingResponse struct {
@@ -56,15 +100,85 @@ func skipNoAPIKeys(t *testing.T) {
 	}
 }
 
+// skipResourceCreation skips tests that create resources on the live API, unless allowed.
+// Replayed tests don't create anything, so they always run.
 func skipResourceCreation(t *testing.T) {
-	if allowTestCreate != "true" {
+	if allowTestCreate != "true" && (apikey != "" || recordCassettes) {
 		t.Skipf("Test that creates resources ($$$) skipped unless ALLOW_TEST_CREATE=true is defined")
 	}
 }
 
-func TestPing(t *testing.T) {
-	skipNoAPIKeys(t)
+var recordCassettes = os.Getenv("CLOUDSHARE_CASSETTES") == "record"
+
+/*
+liveClient returns a client for tests against the live API:
+
+  - with CLOUDSHARE_CASSETTES=record and credentials, it records the test's interactions
+    to testdata/cassettes/<test name>.json
+  - with credentials, it talks to the live API
+  - without credentials, it replays the test's cassette, or skips the test if there's none
+*/
+func liveClient(t *testing.T) *Client {
+	path := filepath.Join("testdata", "cassettes", strings.ReplaceAll(t.Name(), "/", "_")+".json")
+	mode := cassette.ModeReplay
+	switch {
+	case recordCassettes:
+		skipNoAPIKeys(t)
+		mode = cassette.ModeRecord
+	case apikey != "" && apiid != "":
+		return getClient()
+	default:
+		if _, err := os.Stat(path); err != nil {
+			t.Skipf("test only runs with actual credentials, or a cassette recorded with CLOUDSHARE_CASSETTES=record")
+		}
+	}
+
+	recorder, err := cassette.New(path, mode)
+	require.NoError(t, err)
+	recorder.RedactedFields = DefaultRedactedFields()
+	t.Cleanup(func() {
+		if !t.Failed() && !t.Skipped() {
+			require.NoError(t, recorder.Stop())
+		}
+	})
 	c := getClient()
+	if mode == cassette.ModeReplay {
+		c.APIKey, c.APIID = "replayed-api-key", "replayed-api-id"
+	}
+	c.HTTPClient = recorder.Client()
+	return c
+}
+
+func TestCassette(t *testing.T) {
+	c := newLocalClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[{"id": "RE123", "name": "Miami", "apiKey": "secret-key"}]`))
+	})
+	c.APIID, c.APIKey = "api_id", "api_key"
+	path := filepath.Join(t.TempDir(), "regions.json")
+	recorder, err := cassette.New(path, cassette.ModeRecord)
+	require.NoError(t, err)
+	recorder.Transport = c.HTTPClient.Transport
+	c.HTTPClient = recorder.Client()
+	regions := []Region{}
+	require.Nil(t, c.GetRegions(&regions))
+	require.NoError(t, recorder.Stop())
+
+	recorded, err := os.ReadFile(path)
+	require.NoError(t, err)
+	require.NotContains(t, string(recorded), "hmac")
+	require.NotContains(t, string(recorded), c.APIID)
+	require.NotContains(t, string(recorded), "secret-key")
+
+	replayer, err := cassette.New(path, cassette.ModeReplay)
+	require.NoError(t, err)
+	offline := &Client{APIKey: "other-key", APIID: "other-id", HTTPClient: replayer.Client()}
+	regions = []Region{}
+	require.Nil(t, offline.GetRegions(&regions))
+	require.Equal(t, "Miami", regions[0].Name)
+}
+
+func TestPing(t *testing.T) {
+	c := liveClient(t)
 
 	res, apierr := c.Request("GET", "ping", nil, nil)
 	require.Nil(t, apierr, "failed to ping %s", apierr)
@@ -74,9 +188,81 @@ func TestPing(t *testing.T) {
 	require.Equal(t, "Pong", parsed.Result)
 }
 
+// newLocalClient returns a client that talks to a local TLS server running handler
+func newLocalClient(t *testing.T, handler http.HandlerFunc) *Client {
+	server := httptest.NewTLSServer(handler)
+	t.Cleanup(server.Close)
+	u, err := url.Parse(server.URL)
+	require.NoError(t, err)
+	c := getClient()
+	c.APIHost = u.Host
+	c.HTTPClient = server.Client()
+	return c
+}
+
+func TestRequestWithContextDeadline(t *testing.T) {
+	c := newLocalClient(t, func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	regions := []Region{}
+	require.NotNil(t, c.GetRegionsWithContext(ctx, &regions))
+	require.True(t, time.Since(start) < 5*time.Second, "request was not aborted by the deadline")
+}
+
+func TestRequestWithContextCancelDuringBodyRead(t *testing.T) {
+	c := newLocalClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte(`[{"id": "RE`))
+		w.(http.Flusher).Flush()
+		<-r.Context().Done()
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	time.AfterFunc(50*time.Millisecond, cancel)
+
+	_, err := c.RequestWithContext(ctx, "GET", "regions", nil, nil)
+	require.NotNil(t, err)
+	require.Contains(t, err.Error(), "context canceled")
+}
+
+func TestRequestWithContext(t *testing.T) {
+	c := newLocalClient(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v3/regions", r.URL.Path)
+		w.Write([]byte(`[{"id": "REKolD1-ab84YIxODeMGob9A2", "name": "Miami"}]`))
+	})
+	regions := []Region{}
+	require.Nil(t, c.GetRegionsWithContext(context.Background(), &regions))
+	require.Equal(t, "Miami", regions[0].Name)
+}
+
+func TestDo(t *testing.T) {
+	c := newLocalClient(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "POST", r.Method)
+		require.Equal(t, "/api/v3/class/CL123/students", r.URL.Path)
+		require.Equal(t, "true", r.URL.Query().Get("notify"))
+		var student map[string]string
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&student))
+		require.Equal(t, "student1@test.com", student["email"])
+		w.Write([]byte(`{"id": "ST456", "email": "student1@test.com"}`))
+	})
+
+	type Student struct {
+		ID    string `json:"id"`
+		Email string `json:"email"`
+	}
+	query := url.Values{}
+	query.Set("notify", "true")
+	student, err := Do[Student](context.Background(), c, "POST", "class/CL123/students", &query, Student{Email: "student1@test.com"})
+	require.Nil(t, err)
+	require.Equal(t, Student{ID: "ST456", Email: "student1@test.com"}, student)
+}
+
 func requireGreaterThan(t *testing.T, left int, right int) {
 	if left <= right {
-		t.Error("Expecting %d > %d", left, right)
+		t.Errorf("Expecting %d > %d", left, right)
 	}
 }
 
@@ -90,8 +276,7 @@ func TestBadKeys(t *testing.T) {
 }
 
 func TestGetBlueprints(t *testing.T) {
-	skipNoAPIKeys(t)
-	c := getClient()
+	c := liveClient(t)
 
 	var projects = []Project{}
 	apierr := c.GetProjects(&projects)
@@ -119,18 +304,16 @@ func TestGetBlueprints(t *testing.T) {
 }
 
 func TestGetProjectsByFilter(t *testing.T) {
-	skipNoAPIKeys(t)
+	c := liveClient(t)
 
 	var projects = []Project{}
-	c := getClient()
 	apierr := c.GetProjectsByFilter([]string{"WhereUserIsProjectManager"}, &projects)
 	require.Nil(t, apierr, "failed to fetch projects")
 }
 
 func TestGetEnvs(t *testing.T) {
-	skipNoAPIKeys(t)
+	c := liveClient(t)
 	var envs = Environments{}
-	c := getClient()
 	apierr := c.GetEnvironments(true, "allvisible", &envs)
 	require.Nil(t, apierr, "failed to fetch envs")
 
@@ -142,8 +325,7 @@ func TestGetEnvs(t *testing.T) {
 }
 
 func TestGetEnvDetails(t *testing.T) {
-	skipNoAPIKeys(t)
-	c := getClient()
+	c := liveClient(t)
 	env, apierr := c.GetEnvironmentByName(testEnvName)
 	require.Nil(t, apierr, "failed to fetch env by ID")
 	require.NotNil(t, env, "failed to find test env. possibly this test suite hasn't been run with ALLOW_TEST_CREATE?")
@@ -155,8 +337,7 @@ func TestGetEnvDetails(t *testing.T) {
 }
 
 func TestEnvResume(t *testing.T) {
-	skipNoAPIKeys(t)
-	c := getClient()
+	c := liveClient(t)
 	env, apierr := c.GetEnvironmentByName(testEnvName)
 	require.Nil(t, apierr, "failed to fetch env by name")
 	require.NotNil(t, env)
@@ -165,8 +346,7 @@ func TestEnvResume(t *testing.T) {
 }
 
 func TestEnvExtend(t *testing.T) {
-	skipNoAPIKeys(t)
-	c := getClient()
+	c := liveClient(t)
 	env, apierr := c.GetEnvironmentByName(testEnvName)
 	require.NotNil(t, env)
 	require.Nil(t, apierr, "failed to fetch env by name")
@@ -175,11 +355,10 @@ func TestEnvExtend(t *testing.T) {
 }
 
 func TestDeleteEnv(t *testing.T) {
-	skipNoAPIKeys(t)
+	c := liveClient(t)
 	if os.Getenv("TEST_DELETE_ENV") != "true" {
 		t.Skip("Not running delete-env test unless TEST_DELETE_ENV is true")
 	}
-	c := getClient()
 	env, apierr := c.GetEnvironmentByName(testEnvName)
 	require.Nil(t, apierr, "failed to fetch env by name")
 	require.NotNil(t, env)
@@ -187,9 +366,8 @@ func TestDeleteEnv(t *testing.T) {
 }
 
 func TestFindTemplateByName(t *testing.T) {
-	skipNoAPIKeys(t)
+	c := liveClient(t)
 	templates := []VMTemplate{}
-	c := getClient()
 	require.Nil(t, c.GetTemplates(nil, &templates))
 	for _, template := range templates {
 		if template.Name == "Docker - Ubuntu 14.04 Server - SMALL" {
@@ -199,40 +377,34 @@ func TestFindTemplateByName(t *testing.T) {
 	t.Errorf("Docker template not found in list of templates")
 }
 
-func waitForEnvStatus(t *testing.T, envID string, code EnvironmentStatusCode) EnvironmentStatusCode {
-	c := getClient()
-	details := EnvironmentExtended{}
-	for i := 0; i < 10; i++ {
-		require.Nil(t, c.GetEnvironmentExtended(envID, &details))
-		if details.StatusCode == code {
-			return code
-		}
-		t.Logf("Status is still %d, waiting for %d", details.StatusCode, code)
-		time.Sleep(time.Second)
-	}
-
-	return details.StatusCode
-}
-
 func TestWaitForEnvironment(t *testing.T) {
-	c := getClient()
+	c := liveClient(t)
 	env, apierr := c.GetEnvironmentByName(testEnvName)
 	require.Nil(t, apierr, "failed to fetch envs")
 	require.NotNil(t, env, "Test env not found")
 	envID := env.ID
+	opts := &WaitOptions{
+		Interval: time.Second,
+		OnProgress: func(env *EnvironmentExtended) {
+			t.Logf("Status is %d (%s)", env.StatusCode, env.StatusText)
+		},
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Minute)
+	defer cancel()
 	// Suspend and wait for suspended status
 	require.Nil(t, c.EnvironmentSuspend(envID))
-	require.Equal(t, StatusSuspended, waitForEnvStatus(t, envID, StatusSuspended))
+	_, err := c.WaitForEnvironmentStatus(ctx, envID, StatusSuspended, opts)
+	require.Nil(t, err)
 	require.Nil(t, c.EnvironmentResume(envID))
-	require.Equal(t, StatusReady, waitForEnvStatus(t, envID, StatusReady))
+	_, err = c.WaitForReady(ctx, envID, opts)
+	require.Nil(t, err)
 }
 
 func TestPolicies(t *testing.T) {
-	skipNoAPIKeys(t)
+	c := liveClient(t)
 	skipResourceCreation(t)
 
 	var projects = []Project{}
-	c := getClient()
 	apierr := c.GetProjects(&projects)
 	require.Nil(t, apierr, "failed to fetch projects")
 	requireGreaterThan(t, len(projects), 0)
@@ -270,9 +442,8 @@ func TestPolicies(t *testing.T) {
 }
 
 func TestCreateEnv(t *testing.T) {
-	skipNoAPIKeys(t)
+	c := liveClient(t)
 	skipResourceCreation(t)
-	c := getClient()
 	env, apierr := c.GetEnvironmentByName(testEnvName)
 
 	require.Nil(t, apierr, "failed to fetch envs")
@@ -298,7 +469,7 @@ func TestCreateEnv(t *testing.T) {
 	proj1 := projects[0]
 
 	// Find Ubuntu template
-	var ubuntuTemplateID string
+	var ubuntuTemplateID TemplateID
 	for _, t := range templates {
 		if strings.Contains(t.Name, "Ubuntu 16.04") {
 			ubuntuTemplateID = t.ID
@@ -309,7 +480,7 @@ func TestCreateEnv(t *testing.T) {
 	var request = EnvironmentTemplateRequest{
 		Environment: Environment{
 			Name:        testEnvName,
-			Description: "not super important",
+			Description: Ptr("not super important"),
 			ProjectID:   proj1.ID,
 			RegionID:    region1,
 		},
@@ -317,7 +488,7 @@ func TestCreateEnv(t *testing.T) {
 			Type:         2,
 			Name:         "vm1",
 			TemplateVMID: ubuntuTemplateID,
-			Description:  "my little vm",
+			Description:  Ptr("my little vm"),
 		}},
 	}
 
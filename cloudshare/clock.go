@@ -0,0 +1,58 @@
+package cloudshare
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// clockSkewThreshold is the smallest difference between the request timestamp and the server's clock
+// that's considered the cause of an authentication failure
+const clockSkewThreshold = 5 * time.Second
+
+// clockOffsets holds the measured clock offset of every API host, shared by all clients
+var clockOffsets sync.Map // host -> *atomic.Int64
+
+func (c *Client) clockOffset() *atomic.Int64 {
+	offset, _ := clockOffsets.LoadOrStore(c.host(), new(atomic.Int64))
+	return offset.(*atomic.Int64)
+}
+
+// ClockOffset returns how far the API server's clock is ahead of the local clock (negative when behind).
+// It's measured from the Date header of a request rejected because of clock skew, and is 0 until then.
+// Requests are signed with the local time adjusted by this offset.
+func (c *Client) ClockOffset() time.Duration {
+	return time.Duration(c.clockOffset().Load())
+}
+
+type signingOffsetKey struct{}
+
+// SigningOffset returns the offset a Signer should add to the local time when signing a request with ctx,
+// to compensate for the server's clock skew (see Client.ClockOffset)
+func SigningOffset(ctx context.Context) time.Duration {
+	offset, _ := ctx.Value(signingOffsetKey{}).(time.Duration)
+	return offset
+}
+
+// detectClockSkew checks whether a request signed with offset was rejected because of clock skew.
+// If so, it returns the server's measured offset, and sets the error's ClockSkew.
+func detectClockSkew(offset time.Duration, res *APIResponse, err error) (time.Duration, bool) {
+	var apiErr *APIError
+	if res == nil || !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusUnauthorized {
+		return 0, false
+	}
+	serverTime, parseErr := http.ParseTime(res.Headers.Get("Date"))
+	if parseErr != nil {
+		return 0, false
+	}
+	measured := serverTime.Sub(time.Now())
+	skew := measured - offset
+	if skew.Abs() < clockSkewThreshold {
+		return 0, false
+	}
+	apiErr.ClockSkew = skew
+	return measured, true
+}
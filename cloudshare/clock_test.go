@@ -0,0 +1,91 @@
+package cloudshare
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"regexp"
+	"strconv"
+	"testing"
+	"time"
+)
+
+var timestampPattern = regexp.MustCompile(`timestamp:(\d+);`)
+
+// newSkewedClient serves requests from a server whose clock is ahead by serverOffset,
+// and rejects requests whose timestamp is more than 30s off when strict is set
+func newSkewedClient(t *testing.T, serverOffset time.Duration, strict bool, requests *int) *Client {
+	return newLocalClient(t, func(w http.ResponseWriter, r *http.Request) {
+		*requests++
+		now := time.Now().Add(serverOffset)
+		w.Header().Set("Date", now.UTC().Format(http.TimeFormat))
+		timestamp, _ := strconv.ParseInt(timestampPattern.FindStringSubmatch(r.Header.Get("Authorization"))[1], 10, 64)
+		if !strict || now.Sub(time.Unix(timestamp, 0)).Abs() > 30*time.Second {
+			w.WriteHeader(http.StatusUnauthorized)
+			w.Write([]byte(`{"message": "Unauthorized"}`))
+			return
+		}
+		w.Write([]byte(`[]`))
+	})
+}
+
+func TestClockSkewCompensation(t *testing.T) {
+	requests := 0
+	c := newSkewedClient(t, -time.Hour, true, &requests)
+	assert.Zero(t, c.ClockOffset())
+
+	_, err := c.Request("GET", "projects", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 2, requests, "the request is re-signed once")
+	assert.InDelta(t, -time.Hour, c.ClockOffset(), float64(5*time.Second))
+
+	_, err = c.Request("GET", "projects", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, 3, requests, "later requests are signed with the measured offset")
+
+	other := newLocalClient(t, nil)
+	assert.Zero(t, other.ClockOffset(), "offsets are per host")
+}
+
+func TestClockSkewUnauthorizedAfterCorrection(t *testing.T) {
+	requests := 0
+	c := newSkewedClient(t, time.Hour, false, &requests)
+	c.Retry = DefaultRetryPolicy()
+
+	_, err := c.Request("GET", "projects", nil, nil)
+	assert.True(t, errors.Is(err, ErrUnauthorized))
+	assert.False(t, errors.Is(err, ErrClockSkew), "the server accepted the corrected clock, so skew isn't the cause")
+	assert.Equal(t, 2, requests)
+}
+
+func TestClockSkewNotDetected(t *testing.T) {
+	requests := 0
+	c := newSkewedClient(t, time.Second, false, &requests)
+
+	_, err := c.Request("GET", "projects", nil, nil)
+	assert.True(t, errors.Is(err, ErrUnauthorized))
+	assert.False(t, errors.Is(err, ErrClockSkew))
+	assert.Equal(t, 1, requests, "small differences aren't retried")
+	assert.Zero(t, c.ClockOffset())
+}
+
+func TestDetectClockSkew(t *testing.T) {
+	headers := http.Header{}
+	headers.Set("Date", time.Now().Add(time.Hour).UTC().Format(http.TimeFormat))
+	res := &APIResponse{StatusCode: http.StatusUnauthorized, Headers: headers}
+	err := &APIError{StatusCode: http.StatusUnauthorized}
+
+	measured, ok := detectClockSkew(0, res, err)
+	require.True(t, ok)
+	assert.InDelta(t, time.Hour, measured, float64(5*time.Second))
+	assert.True(t, errors.Is(err, ErrClockSkew))
+	assert.Contains(t, err.Error(), "the local clock is off by")
+
+	_, ok = detectClockSkew(time.Hour, res, &APIError{StatusCode: http.StatusUnauthorized})
+	assert.False(t, ok, "already compensated")
+	_, ok = detectClockSkew(0, res, &APIError{StatusCode: http.StatusForbidden})
+	assert.False(t, ok)
+	_, ok = detectClockSkew(0, &APIResponse{Headers: http.Header{}}, &APIError{StatusCode: http.StatusUnauthorized})
+	assert.False(t, ok, "no Date header")
+}
@@ -0,0 +1,90 @@
+package cloudshare_test
+
+import (
+	"context"
+	"github.com/cloudshare/go-sdk/cloudshare"
+	"github.com/cloudshare/go-sdk/cloudshare/cstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"log/slog"
+	"sync"
+	"testing"
+	"time"
+)
+
+// TestConcurrentClient shares one client between goroutines calling the typed wrappers.
+// Run with -race.
+func TestConcurrentClient(t *testing.T) {
+	server := cstest.NewServer()
+	t.Cleanup(server.Close)
+
+	var calls sync.Map
+	counter := func(next cloudshare.Handler) cloudshare.Handler {
+		return func(call *cloudshare.Call) (*cloudshare.APIResponse, error) {
+			calls.Store(call.Path, true)
+			return next(call)
+		}
+	}
+	c, err := cloudshare.NewClient(
+		cloudshare.WithCredentials(server.APIID, server.APIKey),
+		cloudshare.WithBaseURL(server.BaseURL()),
+		cloudshare.WithHTTPClient(server.Client().HTTPClient),
+		cloudshare.WithRetry(cloudshare.DefaultRetryPolicy()),
+		cloudshare.WithMiddleware(counter),
+		cloudshare.WithLogger(slog.New(slog.NewTextHandler(io.Discard, &slog.HandlerOptions{Level: slog.LevelDebug})), true),
+	)
+	require.NoError(t, err)
+
+	projects := []cloudshare.Project{}
+	require.NoError(t, c.GetProjects(&projects))
+	templates := []cloudshare.VMTemplate{}
+	require.NoError(t, c.GetTemplates(nil, &templates))
+
+	const workers = 16
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*10)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+
+			request := cloudshare.EnvironmentTemplateRequest{
+				Environment: cloudshare.Environment{Name: "env" + string(rune('a'+i)), ProjectID: projects[0].ID},
+				ItemsCart:   []cloudshare.VM{{Name: "vm", TemplateVMID: templates[0].ID}},
+			}
+			response := cloudshare.CreateTemplateEnvResponse{}
+			errs <- c.EnvironmentCreateFromTemplateWithContext(ctx, &request, &response)
+			env, err := c.WaitForReady(ctx, response.EnvironmentID, &cloudshare.WaitOptions{Interval: time.Millisecond})
+			errs <- err
+			if err != nil {
+				return
+			}
+			errs <- c.RebootVMWithContext(ctx, env.Vms[0].ID)
+			errs <- c.EnvironmentExtendWithContext(ctx, env.ID)
+			errs <- c.GetRegionsWithContext(ctx, &[]cloudshare.Region{})
+			errs <- c.GetBlueprintsWithContext(ctx, projects[0].ID, &[]cloudshare.Blueprint{})
+			for _, err := range c.AllTemplates(ctx, nil, 1) {
+				errs <- err
+			}
+			_, err = c.GetEnvironmentByNameWithContext(ctx, request.Environment.Name)
+			errs <- err
+			errs <- c.EnvironmentDeleteWithContext(ctx, env.ID)
+		}(i)
+	}
+	go func() {
+		wg.Wait()
+		close(errs)
+	}()
+	for err := range errs {
+		assert.NoError(t, err)
+	}
+
+	_, ok := calls.Load("envs/actions/getextended")
+	assert.True(t, ok)
+	envs := cloudshare.Environments{}
+	require.NoError(t, c.GetEnvironments(true, "allvisible", &envs))
+	assert.Empty(t, envs)
+}
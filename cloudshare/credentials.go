@@ -0,0 +1,221 @@
+package cloudshare
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+/*
+Credentials are an API ID and key, found on the user details page.
+
+A client looks them up in this order:
+
+ 1. Client.APIID and Client.APIKey, when both are set
+ 2. Client.Credentials, when set
+ 3. for clients created by NewClient, DefaultCredentials(): the CLOUDSHARE_API_ID and CLOUDSHARE_API_KEY
+    environment variables, then the credentials file profile named by CLOUDSHARE_PROFILE (or "default")
+
+The credentials file (~/.cloudshare/credentials, or the file named by CLOUDSHARE_CREDENTIALS_FILE)
+is an INI file with one section per profile:
+
+	[default]
+	api_id = your API id here
+	api_key = your API key here
+
+	[sandbox]
+	api_id = ...
+	api_key = ...
+*/
+type Credentials struct {
+	APIID  string
+	APIKey string
+}
+
+// IsSet reports whether both the ID and key are set
+func (c Credentials) IsSet() bool {
+	return c.APIID != "" && c.APIKey != ""
+}
+
+// CredentialsProvider returns API credentials. Providers that have no credentials return an error
+// wrapping ErrMissingCredentials, so that a CredentialsChain moves on to the next provider.
+// Providers set as Client.Credentials are called for every request, and must be safe for concurrent use.
+type CredentialsProvider interface {
+	Credentials(ctx context.Context) (Credentials, error)
+}
+
+// CredentialsProviderFunc adapts a function to a CredentialsProvider
+type CredentialsProviderFunc func(ctx context.Context) (Credentials, error)
+
+func (f CredentialsProviderFunc) Credentials(ctx context.Context) (Credentials, error) {
+	return f(ctx)
+}
+
+// StaticCredentials returns a provider of fixed credentials
+func StaticCredentials(apiID string, apiKey string) CredentialsProvider {
+	return CredentialsProviderFunc(func(ctx context.Context) (Credentials, error) {
+		creds := Credentials{APIID: apiID, APIKey: apiKey}
+		if !creds.IsSet() {
+			return Credentials{}, ErrMissingCredentials
+		}
+		return creds, nil
+	})
+}
+
+// Environment variables read by EnvCredentials and ProfileCredentials
+const (
+	EnvAPIID           = "CLOUDSHARE_API_ID"
+	EnvAPIKey          = "CLOUDSHARE_API_KEY"
+	EnvProfile         = "CLOUDSHARE_PROFILE"
+	EnvCredentialsFile = "CLOUDSHARE_CREDENTIALS_FILE"
+)
+
+// EnvCredentials returns a provider of the credentials in the CLOUDSHARE_API_ID and CLOUDSHARE_API_KEY environment variables
+func EnvCredentials() CredentialsProvider {
+	return CredentialsProviderFunc(func(ctx context.Context) (Credentials, error) {
+		creds := Credentials{APIID: os.Getenv(EnvAPIID), APIKey: os.Getenv(EnvAPIKey)}
+		if !creds.IsSet() {
+			return Credentials{}, fmt.Errorf("%w: %s and %s aren't set", ErrMissingCredentials, EnvAPIID, EnvAPIKey)
+		}
+		return creds, nil
+	})
+}
+
+// ProfileCredentials reads credentials of a named profile from the credentials file
+type ProfileCredentials struct {
+	// Path defaults to $CLOUDSHARE_CREDENTIALS_FILE, or ~/.cloudshare/credentials
+	Path string
+	// Profile defaults to $CLOUDSHARE_PROFILE, or "default"
+	Profile string
+}
+
+// DefaultCredentialsPath returns the path of the credentials file
+func DefaultCredentialsPath() (string, error) {
+	if path := os.Getenv(EnvCredentialsFile); path != "" {
+		return path, nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, ".cloudshare", "credentials"), nil
+}
+
+// Credentials reads the profile's credentials. A missing file or default profile is reported as
+// ErrMissingCredentials, but a missing profile that was selected by name is an error.
+func (p ProfileCredentials) Credentials(ctx context.Context) (Credentials, error) {
+	profile := p.Profile
+	if profile == "" {
+		profile = os.Getenv(EnvProfile)
+	}
+	selected := profile != ""
+	if !selected {
+		profile = "default"
+	}
+
+	path := p.Path
+	if path == "" {
+		var err error
+		if path, err = DefaultCredentialsPath(); err != nil {
+			return Credentials{}, fmt.Errorf("%w: %s", ErrMissingCredentials, err)
+		}
+	}
+	profiles, err := readProfiles(path)
+	if errors.Is(err, os.ErrNotExist) && !selected {
+		return Credentials{}, fmt.Errorf("%w: no credentials file at %s", ErrMissingCredentials, path)
+	}
+	if err != nil {
+		return Credentials{}, err
+	}
+
+	values, ok := profiles[profile]
+	if !ok {
+		if !selected {
+			return Credentials{}, fmt.Errorf("%w: no profile %q in %s", ErrMissingCredentials, profile, path)
+		}
+		return Credentials{}, fmt.Errorf("cloudshare: no profile %q in %s", profile, path)
+	}
+	creds := Credentials{APIID: values["api_id"], APIKey: values["api_key"]}
+	if !creds.IsSet() {
+		return Credentials{}, fmt.Errorf("cloudshare: profile %q in %s must set api_id and api_key", profile, path)
+	}
+	return creds, nil
+}
+
+// readProfiles parses an INI file into its sections' keys and values.
+// Blank lines and lines starting with # or ; are ignored.
+func readProfiles(path string) (map[string]map[string]string, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	profiles := map[string]map[string]string{}
+	var section map[string]string
+	scanner := bufio.NewScanner(file)
+	for lineNumber := 1; scanner.Scan(); lineNumber++ {
+		line := strings.TrimSpace(scanner.Text())
+		switch {
+		case line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, ";"):
+		case strings.HasPrefix(line, "[") && strings.HasSuffix(line, "]"):
+			name := strings.TrimSpace(line[1 : len(line)-1])
+			if profiles[name] == nil {
+				profiles[name] = map[string]string{}
+			}
+			section = profiles[name]
+		default:
+			key, value, ok := strings.Cut(line, "=")
+			if !ok || section == nil {
+				return nil, fmt.Errorf("cloudshare: %s:%d: expecting [profile] or key = value", path, lineNumber)
+			}
+			section[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return profiles, scanner.Err()
+}
+
+// CredentialsChain returns the credentials of the first provider that has them.
+// Providers returning ErrMissingCredentials are skipped; any other error stops the lookup.
+type CredentialsChain []CredentialsProvider
+
+func (chain CredentialsChain) Credentials(ctx context.Context) (Credentials, error) {
+	var missing []string
+	for _, provider := range chain {
+		creds, err := provider.Credentials(ctx)
+		if err == nil {
+			return creds, nil
+		}
+		if !errors.Is(err, ErrMissingCredentials) {
+			return Credentials{}, err
+		}
+		if err != ErrMissingCredentials {
+			missing = append(missing, strings.TrimPrefix(err.Error(), ErrMissingCredentials.Error()+": "))
+		}
+	}
+	if len(missing) == 0 {
+		return Credentials{}, ErrMissingCredentials
+	}
+	return Credentials{}, fmt.Errorf("%w (%s)", ErrMissingCredentials, strings.Join(missing, "; "))
+}
+
+// DefaultCredentials returns the default chain: environment variables, then the credentials file profile,
+// then the given custom providers
+func DefaultCredentials(custom ...CredentialsProvider) CredentialsChain {
+	return append(CredentialsChain{EnvCredentials(), ProfileCredentials{}}, custom...)
+}
+
+// credentials returns the credentials to sign a request with
+func (c *Client) credentials(ctx context.Context) (Credentials, error) {
+	if c.APIID != "" && c.APIKey != "" {
+		return Credentials{APIID: c.APIID, APIKey: c.APIKey}, nil
+	}
+	if c.Credentials != nil {
+		return c.Credentials.Credentials(ctx)
+	}
+	return Credentials{APIID: c.APIID, APIKey: c.APIKey}, nil
+}
@@ -0,0 +1,143 @@
+package cloudshare
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// isolateCredentials hides the environment's credentials from the default chain
+func isolateCredentials(t *testing.T) string {
+	path := filepath.Join(t.TempDir(), "credentials")
+	t.Setenv(EnvAPIID, "")
+	t.Setenv(EnvAPIKey, "")
+	t.Setenv(EnvProfile, "")
+	t.Setenv(EnvCredentialsFile, path)
+	return path
+}
+
+const testCredentialsFile = `
+# CloudShare accounts
+[default]
+api_id = default-id
+api_key = default-key
+
+[sandbox]
+api_id=sandbox-id
+api_key=sandbox-key
+
+; incomplete
+[broken]
+api_id = broken-id
+`
+
+func TestProfileCredentials(t *testing.T) {
+	path := isolateCredentials(t)
+	ctx := context.Background()
+
+	_, err := ProfileCredentials{}.Credentials(ctx)
+	assert.True(t, errors.Is(err, ErrMissingCredentials), "no file")
+	_, err = ProfileCredentials{Profile: "sandbox"}.Credentials(ctx)
+	assert.False(t, errors.Is(err, ErrMissingCredentials), "a named profile must exist")
+
+	require.NoError(t, os.WriteFile(path, []byte(testCredentialsFile), 0600))
+	creds, err := ProfileCredentials{}.Credentials(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, Credentials{APIID: "default-id", APIKey: "default-key"}, creds)
+
+	creds, err = ProfileCredentials{Profile: "sandbox"}.Credentials(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, Credentials{APIID: "sandbox-id", APIKey: "sandbox-key"}, creds)
+
+	t.Setenv(EnvProfile, "sandbox")
+	creds, err = ProfileCredentials{}.Credentials(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "sandbox-id", creds.APIID)
+
+	_, err = ProfileCredentials{Profile: "broken"}.Credentials(ctx)
+	assert.Error(t, err)
+	_, err = ProfileCredentials{Profile: "production"}.Credentials(ctx)
+	assert.Error(t, err)
+	assert.False(t, errors.Is(err, ErrMissingCredentials))
+
+	require.NoError(t, os.WriteFile(path, []byte("api_id = orphan\n"), 0600))
+	_, err = ProfileCredentials{}.Credentials(ctx)
+	assert.Contains(t, err.Error(), ":1:")
+}
+
+func TestDefaultCredentialsChain(t *testing.T) {
+	path := isolateCredentials(t)
+	ctx := context.Background()
+
+	_, err := DefaultCredentials().Credentials(ctx)
+	assert.True(t, errors.Is(err, ErrMissingCredentials))
+	assert.Contains(t, err.Error(), EnvAPIID)
+
+	custom := StaticCredentials("custom-id", "custom-key")
+	creds, err := DefaultCredentials(custom).Credentials(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "custom-id", creds.APIID)
+
+	require.NoError(t, os.WriteFile(path, []byte(testCredentialsFile), 0600))
+	creds, err = DefaultCredentials(custom).Credentials(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "default-id", creds.APIID, "the profile comes before custom providers")
+
+	t.Setenv(EnvAPIID, "env-id")
+	t.Setenv(EnvAPIKey, "env-key")
+	creds, err = DefaultCredentials(custom).Credentials(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, "env-id", creds.APIID, "the environment comes first")
+
+	failing := CredentialsProviderFunc(func(ctx context.Context) (Credentials, error) {
+		return Credentials{}, errors.New("vault is sealed")
+	})
+	_, err = CredentialsChain{failing, custom}.Credentials(ctx)
+	assert.EqualError(t, err, "vault is sealed")
+}
+
+func TestNewClientCredentials(t *testing.T) {
+	path := isolateCredentials(t)
+	require.NoError(t, os.WriteFile(path, []byte(testCredentialsFile), 0600))
+
+	c, err := NewClient()
+	require.NoError(t, err)
+	assert.Equal(t, "default-id", c.APIID)
+
+	c, err = NewClient(WithCredentials("explicit-id", "explicit-key"))
+	require.NoError(t, err)
+	assert.Equal(t, "explicit-id", c.APIID)
+
+	sandbox, err := c.With(WithProfile("sandbox"))
+	require.NoError(t, err)
+	creds, err := sandbox.credentials(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "sandbox-id", creds.APIID)
+
+	_, err = NewClient(WithProfile("production"))
+	assert.Error(t, err)
+}
+
+func TestRequestSignedWithProvider(t *testing.T) {
+	c := newLocalClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if !strings.Contains(r.Header.Get("Authorization"), "userapiid:provided-id;") {
+			w.WriteHeader(http.StatusUnauthorized)
+		}
+	})
+	c.APIID, c.APIKey = "", ""
+	c.Credentials = StaticCredentials("provided-id", "provided-key")
+	_, err := c.Request("GET", "ping", nil, nil)
+	assert.NoError(t, err)
+
+	c.Credentials = CredentialsProviderFunc(func(ctx context.Context) (Credentials, error) {
+		return Credentials{}, ErrMissingCredentials
+	})
+	_, err = c.Request("GET", "ping", nil, nil)
+	assert.True(t, errors.Is(err, ErrMissingCredentials))
+}
@@ -0,0 +1,375 @@
+package cstest
+
+import (
+	"encoding/json"
+	"github.com/cloudshare/go-sdk/cloudshare"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+func (s *Server) ping(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]string{"result": "Pong"})
+}
+
+func (s *Server) getRegions(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, s.regions)
+}
+
+func (s *Server) getTemplates(w http.ResponseWriter, r *http.Request) {
+	query := r.URL.Query()
+	ret := []cloudshare.VMTemplate{}
+	for _, template := range s.templates {
+		if regionID := query.Get("regionId"); regionID != "" && string(template.RegionID) != regionID {
+			continue
+		}
+		if templateType := query.Get("templateType"); templateType != "" && strconv.Itoa(template.Type) != templateType {
+			continue
+		}
+		ret = append(ret, template)
+	}
+
+	skip, _ := strconv.Atoi(query.Get("skip"))
+	if skip > len(ret) {
+		skip = len(ret)
+	}
+	ret = ret[skip:]
+	if take, _ := strconv.Atoi(query.Get("take")); take > 0 && take < len(ret) {
+		ret = ret[:take]
+	}
+	writeJSON(w, http.StatusOK, ret)
+}
+
+func (s *Server) getProjects(w http.ResponseWriter, r *http.Request) {
+	ret := []cloudshare.Project{}
+	for _, proj := range s.projects {
+		ret = append(ret, proj.Project)
+	}
+	writeJSON(w, http.StatusOK, ret)
+}
+
+// requireProject writes a 404 and returns nil if the request's project doesn't exist
+func (s *Server) requireProject(w http.ResponseWriter, r *http.Request) *project {
+	proj := s.project(cloudshare.ProjectID(r.PathValue("projectId")))
+	if proj == nil {
+		writeError(w, http.StatusNotFound, "NotFound", "Project not found")
+	}
+	return proj
+}
+
+func (s *Server) getProjectDetails(w http.ResponseWriter, r *http.Request) {
+	proj := s.requireProject(w, r)
+	if proj == nil {
+		return
+	}
+	details := cloudshare.ProjectDetails{
+		ID:           proj.ID,
+		Name:         proj.Name,
+		IsActive:     proj.IsActive,
+		CanAddPolicy: true,
+	}
+	details.EnvironmentResourceQuota.CPUCount = 16
+	details.EnvironmentResourceQuota.MemorySizeMB = 32768
+	details.EnvironmentResourceQuota.DiskSizeMB = 512000
+	writeJSON(w, http.StatusOK, details)
+}
+
+func (s *Server) getBlueprints(w http.ResponseWriter, r *http.Request) {
+	proj := s.requireProject(w, r)
+	if proj == nil {
+		return
+	}
+	ret := append([]cloudshare.Blueprint{}, proj.blueprints...)
+	writeJSON(w, http.StatusOK, ret)
+}
+
+func (s *Server) getBlueprintDetails(w http.ResponseWriter, r *http.Request) {
+	proj := s.requireProject(w, r)
+	if proj == nil {
+		return
+	}
+	for _, blueprint := range proj.blueprints {
+		if string(blueprint.ID) == r.PathValue("blueprintId") {
+			writeJSON(w, http.StatusOK, cloudshare.BlueprintDetails{
+				ID:               blueprint.ID,
+				Name:             blueprint.Name,
+				NumberOfMachines: blueprint.NumberOfMachines,
+				CreationDate:     blueprint.CreationDate,
+			})
+			return
+		}
+	}
+	writeError(w, http.StatusNotFound, "NotFound", "Blueprint not found")
+}
+
+func (s *Server) getPolicies(w http.ResponseWriter, r *http.Request) {
+	proj := s.requireProject(w, r)
+	if proj == nil {
+		return
+	}
+	ret := []cloudshare.Policy{}
+	for _, policy := range s.policies {
+		if policy.ProjectID == proj.ID {
+			ret = append(ret, policy)
+		}
+	}
+	writeJSON(w, http.StatusOK, ret)
+}
+
+func (s *Server) createPolicy(w http.ResponseWriter, r *http.Request) {
+	var request cloudshare.PolicyRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, "BadRequest", "Invalid policy: "+err.Error())
+		return
+	}
+	if s.project(request.ProjectID) == nil {
+		writeError(w, http.StatusNotFound, "NotFound", "Project not found")
+		return
+	}
+	policy := cloudshare.Policy{
+		ID:                       cloudshare.PolicyID(s.newID("PO")),
+		Name:                     request.Name,
+		ProjectID:                request.ProjectID,
+		AllowEnvironmentCreation: true,
+	}
+	s.policies = append(s.policies, policy)
+	writeJSON(w, http.StatusOK, cloudshare.PolicyCreationResponse{ID: policy.ID, Name: policy.Name})
+}
+
+func (env *environment) brief() cloudshare.Environment {
+	ret := cloudshare.Environment{
+		ID:         env.ext.ID,
+		Name:       env.ext.Name,
+		ProjectID:  env.ext.ProjectID,
+		RegionID:   env.ext.RegionID,
+		OwnerEmail: env.ext.OwnerEmail,
+		Status:     env.ext.StatusText,
+	}
+	if env.ext.PolicyID != "" {
+		ret.PolicyID = cloudshare.Ptr(env.ext.PolicyID)
+	}
+	if env.ext.Description != "" {
+		ret.Description = cloudshare.Ptr(env.ext.Description)
+	}
+	return ret
+}
+
+func (s *Server) getEnvironments(w http.ResponseWriter, r *http.Request) {
+	ret := cloudshare.Environments{}
+	for _, env := range s.envs {
+		if env.ext.StatusCode != cloudshare.StatusDeleted {
+			ret = append(ret, env.brief())
+		}
+	}
+	writeJSON(w, http.StatusOK, ret)
+}
+
+// requireEnvironment writes a 404 and returns nil if the environment doesn't exist or was deleted
+func (s *Server) requireEnvironment(w http.ResponseWriter, envID string) *environment {
+	env := s.environment(cloudshare.EnvironmentID(envID))
+	if env == nil || env.ext.StatusCode == cloudshare.StatusDeleted {
+		writeError(w, http.StatusNotFound, "NotFound", "Environment not found")
+		return nil
+	}
+	return env
+}
+
+func (s *Server) getEnvironment(w http.ResponseWriter, r *http.Request) {
+	if env := s.requireEnvironment(w, r.PathValue("envId")); env != nil {
+		writeJSON(w, http.StatusOK, env.brief())
+	}
+}
+
+func (s *Server) getEnvironmentExtended(w http.ResponseWriter, r *http.Request) {
+	env := s.requireEnvironment(w, r.URL.Query().Get("envId"))
+	if env == nil {
+		return
+	}
+	env.poll(s.SettlePolls)
+	writeJSON(w, http.StatusOK, env.ext)
+}
+
+type createEnvVM struct {
+	ID       cloudshare.VMID `json:"id"`
+	Name     string          `json:"name"`
+	HostName string          `json:"hostName"`
+}
+
+type createEnvResponse struct {
+	EnvironmentID cloudshare.EnvironmentID `json:"environmentId"`
+	Vms           []createEnvVM            `json:"vms"`
+}
+
+func (s *Server) createEnvironment(w http.ResponseWriter, r *http.Request) {
+	var request cloudshare.EnvironmentTemplateRequest
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, "BadRequest", "Invalid environment request: "+err.Error())
+		return
+	}
+	if request.Environment.Name == "" {
+		writeError(w, http.StatusBadRequest, "BadRequest", "Environment name is required")
+		return
+	}
+	proj := s.project(request.Environment.ProjectID)
+	if proj == nil {
+		writeError(w, http.StatusNotFound, "NotFound", "Project not found")
+		return
+	}
+	for _, env := range s.envs {
+		if env.ext.Name == request.Environment.Name && env.ext.StatusCode != cloudshare.StatusDeleted {
+			writeError(w, http.StatusConflict, "Conflict", "An environment with this name already exists")
+			return
+		}
+	}
+
+	env := &environment{ext: cloudshare.EnvironmentExtended{
+		ID:             cloudshare.EnvironmentID(s.newID("EN")),
+		Name:           request.Environment.Name,
+		ProjectID:      proj.ID,
+		ProjectName:    proj.Name,
+		RegionID:       request.Environment.RegionID,
+		OwnerEmail:     "cstest@example.com",
+		ExpirationTime: cloudshare.Timestamp{Time: time.Now().UTC().Add(8 * time.Hour)},
+		Vms:            []cloudshare.VMAccessDetails{},
+	}}
+	if request.Environment.Description != nil {
+		env.ext.Description = *request.Environment.Description
+	}
+	response := createEnvResponse{EnvironmentID: env.ext.ID, Vms: []createEnvVM{}}
+	for _, item := range request.ItemsCart {
+		if !s.templateExists(item.TemplateVMID) {
+			writeError(w, http.StatusNotFound, "NotFound", "Template not found: "+string(item.TemplateVMID))
+			return
+		}
+		vm := cloudshare.VMAccessDetails{
+			ID:           cloudshare.VMID(s.newID("MC")),
+			Name:         item.Name,
+			Fqdn:         item.Name + ".cstest.local",
+			CPUCount:     1,
+			MemorySizeMB: 1024,
+			DiskSizeGB:   10,
+			Username:     "sysadmin",
+			Password:     "cstest-password",
+			ConsoleToken: "cstest-console-token",
+		}
+		env.ext.Vms = append(env.ext.Vms, vm)
+		response.Vms = append(response.Vms, createEnvVM{ID: vm.ID, Name: vm.Name, HostName: vm.Fqdn})
+	}
+	env.setStatus(cloudshare.StatusPreparing, cloudshare.StatusReady)
+	s.envs = append(s.envs, env)
+	writeJSON(w, http.StatusOK, response)
+}
+
+func (s *Server) templateExists(id cloudshare.TemplateID) bool {
+	for _, template := range s.templates {
+		if template.ID == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Server) deleteEnvironment(w http.ResponseWriter, r *http.Request) {
+	if env := s.requireEnvironment(w, r.PathValue("envId")); env != nil {
+		env.setStatus(cloudshare.StatusDeleted)
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *Server) environmentAction(w http.ResponseWriter, r *http.Request) {
+	action := cloudshare.EnvironmentAction(r.PathValue("action"))
+	switch action {
+	case cloudshare.ActionSuspend, cloudshare.ActionResume, cloudshare.ActionExtend, cloudshare.ActionPostpone:
+	default:
+		writeError(w, http.StatusNotFound, "NotFound", "No such environment action: "+string(action))
+		return
+	}
+
+	env := s.requireEnvironment(w, r.URL.Query().Get("envId"))
+	if env == nil {
+		return
+	}
+	if !actionAllowed(action, env.ext.StatusCode) {
+		writeError(w, http.StatusConflict, "InvalidStatus",
+			"Cannot "+string(action)+" an environment in status "+env.ext.StatusCode.String())
+		return
+	}
+
+	switch action {
+	case cloudshare.ActionSuspend:
+		env.setStatus(cloudshare.StatusStopping, cloudshare.StatusSuspended)
+	case cloudshare.ActionResume:
+		env.setStatus(cloudshare.StatusPreparing, cloudshare.StatusReady)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// actionAllowed decides which statuses the fake accepts each action in. It's kept apart from
+// cloudshare.EnvironmentAction.Allows so that tests of the SDK's validation don't check it against itself.
+func actionAllowed(action cloudshare.EnvironmentAction, status cloudshare.EnvironmentStatusCode) bool {
+	switch action {
+	case cloudshare.ActionSuspend, cloudshare.ActionPostpone:
+		return status == cloudshare.StatusReady || status == cloudshare.StatusInGrace
+	case cloudshare.ActionResume:
+		return status == cloudshare.StatusSuspended
+	case cloudshare.ActionExtend:
+		return status == cloudshare.StatusReady || status == cloudshare.StatusInGrace || status == cloudshare.StatusSuspended
+	}
+	return false
+}
+
+// vm returns the environment and index of a VM, or writes a 404
+func (s *Server) vm(w http.ResponseWriter, vmID cloudshare.VMID) (*environment, int) {
+	for _, env := range s.envs {
+		if env.ext.StatusCode == cloudshare.StatusDeleted {
+			continue
+		}
+		for i, vm := range env.ext.Vms {
+			if vm.ID == vmID {
+				return env, i
+			}
+		}
+	}
+	writeError(w, http.StatusNotFound, "NotFound", "VM not found")
+	return nil, 0
+}
+
+func (s *Server) rebootVM(w http.ResponseWriter, r *http.Request) {
+	env, _ := s.vm(w, cloudshare.VMID(r.URL.Query().Get("vmId")))
+	if env == nil {
+		return
+	}
+	if env.ext.StatusCode != cloudshare.StatusReady {
+		writeError(w, http.StatusConflict, "InvalidStatus", "Cannot reboot a VM of an environment that isn't ready")
+		return
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (s *Server) editVMHardware(w http.ResponseWriter, r *http.Request) {
+	var request struct {
+		VMID          cloudshare.VMID `json:"vmId"`
+		NumCPUs       *int            `json:"numCpus"`
+		MemorySizeMBs *int            `json:"memorySizeMBs"`
+		DiskSizeGBs   *int            `json:"diskSizeGBs"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&request); err != nil {
+		writeError(w, http.StatusBadRequest, "BadRequest", "Invalid hardware request: "+err.Error())
+		return
+	}
+	env, i := s.vm(w, request.VMID)
+	if env == nil {
+		return
+	}
+	vm := &env.ext.Vms[i]
+	if request.NumCPUs != nil {
+		vm.CPUCount = *request.NumCPUs
+	}
+	if request.MemorySizeMBs != nil {
+		vm.MemorySizeMB = *request.MemorySizeMBs
+	}
+	if request.DiskSizeGBs != nil {
+		vm.DiskSizeGB = *request.DiskSizeGBs
+	}
+	writeJSON(w, http.StatusOK, cloudshare.EditVMHardwareResponse{ConflictsFound: false, Conflicts: []string{}})
+}
@@ -0,0 +1,361 @@
+/*
+Package cstest provides an in-process fake of the CloudShare v3 REST API for tests.
+
+The fake validates the cs_sha1 Authorization header of every request (optionally including its timestamp),
+keeps projects, blueprints, policies, templates, regions and environments in memory, and simulates environment
+status transitions (e.g. an environment created from a template is Preparing until polled, then Ready).
+
+Example:
+
+	server := cstest.NewServer()
+	defer server.Close()
+
+	client := server.Client()
+	var regions = []cloudshare.Region{}
+	err := client.GetRegions(&regions)
+*/
+package cstest
+
+import (
+	"crypto/sha1"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"github.com/cloudshare/go-sdk/cloudshare"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// catalogDate is the creation date of templates and blueprints
+var catalogDate = cloudshare.Timestamp{Time: time.Date(2017, 1, 1, 0, 0, 0, 0, time.UTC)}
+
+// Default credentials accepted by a new Server
+const (
+	DefaultAPIKey = "cstest-api-key"
+	DefaultAPIID  = "cstest-api-id"
+)
+
+// Server is a fake CloudShare API server. It is safe for concurrent use.
+type Server struct {
+	*httptest.Server
+
+	// APIKey and APIID are the credentials requests must be signed with
+	APIKey string
+	APIID  string
+
+	// SettlePolls is the number of getextended polls an environment stays in a
+	// transitional status (e.g. Preparing, Stopping) before moving on. Defaults to 0,
+	// meaning every poll advances the environment by one status.
+	SettlePolls int
+
+	// Now is the server's clock, sent in the Date header of every response. Defaults to time.Now.
+	Now func() time.Time
+	// MaxClockSkew is the largest difference between a request's timestamp and Now that's accepted.
+	// Defaults to 0, meaning timestamps aren't checked.
+	MaxClockSkew time.Duration
+
+	mu        sync.Mutex
+	nextID    int
+	requests  int
+	regions   []cloudshare.Region
+	templates []cloudshare.VMTemplate
+	projects  []*project
+	policies  []cloudshare.Policy
+	envs      []*environment
+}
+
+type project struct {
+	cloudshare.Project
+	blueprints []cloudshare.Blueprint
+}
+
+type environment struct {
+	ext     cloudshare.EnvironmentExtended
+	pending []cloudshare.EnvironmentStatusCode
+	polls   int
+}
+
+// NewServer starts a fake API server seeded with one region, one project with a blueprint
+// and a policy, and a VM template
+func NewServer() *Server {
+	s := &Server{
+		APIKey: DefaultAPIKey,
+		APIID:  DefaultAPIID,
+	}
+	s.Server = httptest.NewTLSServer(s.routes())
+
+	region := s.AddRegion("Miami")
+	proj := s.AddProject("Default Project")
+	s.AddBlueprint(proj.ID, "Default Blueprint")
+	s.AddPolicy(proj.ID, "Default Policy")
+	s.AddTemplate("Ubuntu 16.04 Server", region.ID)
+	return s
+}
+
+// Host returns the host:port to use as Client.APIHost
+func (s *Server) Host() string {
+	u, _ := url.Parse(s.URL)
+	return u.Host
+}
+
+// BaseURL returns the base URL to use as Client.BaseURL
+func (s *Server) BaseURL() string {
+	return s.URL + "/api/v3/"
+}
+
+// Client returns a cloudshare.Client configured to talk to the server
+func (s *Server) Client() *cloudshare.Client {
+	return &cloudshare.Client{
+		APIKey:     s.APIKey,
+		APIID:      s.APIID,
+		BaseURL:    s.BaseURL(),
+		HTTPClient: s.Server.Client(),
+	}
+}
+
+// Requests returns the number of API requests the server has handled
+func (s *Server) Requests() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.requests
+}
+
+// newID returns a unique ID with the given CloudShare prefix, e.g. "EN"
+func (s *Server) newID(prefix string) string {
+	s.nextID++
+	return fmt.Sprintf("%s%022d", prefix, s.nextID)
+}
+
+// AddRegion adds a region to the server's catalog
+func (s *Server) AddRegion(name string) cloudshare.Region {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	region := cloudshare.Region{
+		ID:           cloudshare.RegionID(s.newID("RE")),
+		Name:         name,
+		CloudName:    "CloudShare",
+		FriendlyName: name,
+	}
+	s.regions = append(s.regions, region)
+	return region
+}
+
+// AddTemplate adds a VM template available in the given region
+func (s *Server) AddTemplate(name string, regionID cloudshare.RegionID) cloudshare.VMTemplate {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	template := cloudshare.VMTemplate{
+		ID:               cloudshare.TemplateID(s.newID("VM")),
+		Name:             name,
+		Type:             1,
+		RegionID:         regionID,
+		NumberOfMachines: 1,
+		Resources:        cloudshare.Resources{CPUCount: 1, DiskSizeMB: 10240, MemorySizeMB: 1024},
+		CreationDate:     catalogDate,
+	}
+	s.templates = append(s.templates, template)
+	return template
+}
+
+// AddProject adds an active project
+func (s *Server) AddProject(name string) cloudshare.Project {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	proj := &project{Project: cloudshare.Project{
+		ID:       cloudshare.ProjectID(s.newID("PR")),
+		Name:     name,
+		IsActive: true,
+	}}
+	s.projects = append(s.projects, proj)
+	return proj.Project
+}
+
+// AddBlueprint adds a blueprint to a project. It panics if the project doesn't exist.
+func (s *Server) AddBlueprint(projectID cloudshare.ProjectID, name string) cloudshare.Blueprint {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	proj := s.project(projectID)
+	if proj == nil {
+		panic("cstest: no such project " + string(projectID))
+	}
+	blueprint := cloudshare.Blueprint{
+		ID:               cloudshare.BlueprintID(s.newID("BP")),
+		Name:             name,
+		NumberOfMachines: 1,
+		CreationDate:     catalogDate,
+	}
+	proj.blueprints = append(proj.blueprints, blueprint)
+	return blueprint
+}
+
+// AddPolicy adds a policy to a project
+func (s *Server) AddPolicy(projectID cloudshare.ProjectID, name string) cloudshare.Policy {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	policy := cloudshare.Policy{
+		ID:                       cloudshare.PolicyID(s.newID("PO")),
+		Name:                     name,
+		ProjectID:                projectID,
+		AllowEnvironmentCreation: true,
+	}
+	s.policies = append(s.policies, policy)
+	return policy
+}
+
+// Environment returns the current state of an environment, including deleted ones
+func (s *Server) Environment(envID cloudshare.EnvironmentID) (cloudshare.EnvironmentExtended, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	env := s.environment(envID)
+	if env == nil {
+		return cloudshare.EnvironmentExtended{}, false
+	}
+	return env.ext, true
+}
+
+// SetEnvironmentStatus forces an environment into a status, cancelling any pending transitions.
+// Use it to simulate failures such as StatusCreationFailed.
+func (s *Server) SetEnvironmentStatus(envID cloudshare.EnvironmentID, status cloudshare.EnvironmentStatusCode) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if env := s.environment(envID); env != nil {
+		env.setStatus(status)
+	}
+}
+
+func (s *Server) project(id cloudshare.ProjectID) *project {
+	for _, proj := range s.projects {
+		if proj.ID == id {
+			return proj
+		}
+	}
+	return nil
+}
+
+func (s *Server) environment(id cloudshare.EnvironmentID) *environment {
+	for _, env := range s.envs {
+		if env.ext.ID == id {
+			return env
+		}
+	}
+	return nil
+}
+
+func (env *environment) setStatus(status cloudshare.EnvironmentStatusCode, pending ...cloudshare.EnvironmentStatusCode) {
+	env.ext.StatusCode = status
+	env.ext.StatusText = status.String()
+	env.pending = pending
+	env.polls = 0
+	progress := 100
+	if status.IsTransitional() {
+		progress = 50
+	}
+	for i := range env.ext.Vms {
+		env.ext.Vms[i].Progress = progress
+		env.ext.Vms[i].StatusText = env.ext.StatusText
+	}
+}
+
+// poll advances the environment towards its next pending status
+func (env *environment) poll(settlePolls int) {
+	if len(env.pending) == 0 {
+		return
+	}
+	env.polls++
+	if env.polls > settlePolls {
+		env.setStatus(env.pending[0], env.pending[1:]...)
+	}
+}
+
+var authPattern = regexp.MustCompile(`^cs_sha1 userapiid:([^;]*);timestamp:(\d+);token:([^;]*);hmac:([0-9a-f]+)$`)
+
+// authorize validates the request's cs_sha1 Authorization header
+func (s *Server) authorize(r *http.Request) bool {
+	parts := authPattern.FindStringSubmatch(r.Header.Get("Authorization"))
+	if parts == nil || parts[1] != s.APIID {
+		return false
+	}
+	scheme := "https"
+	if r.TLS == nil {
+		scheme = "http"
+	}
+	signedURL := scheme + "://" + r.Host + r.URL.RequestURI()
+	sum := sha1.Sum([]byte(s.APIKey + signedURL + parts[2] + parts[3]))
+	if hex.EncodeToString(sum[:]) != parts[4] {
+		return false
+	}
+	if s.MaxClockSkew == 0 {
+		return true
+	}
+	timestamp, _ := strconv.ParseInt(parts[2], 10, 64)
+	return s.now().Sub(time.Unix(timestamp, 0)).Abs() <= s.MaxClockSkew
+}
+
+func (s *Server) now() time.Time {
+	if s.Now == nil {
+		return time.Now()
+	}
+	return s.Now()
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, code string, message string) {
+	writeJSON(w, status, map[string]string{"code": code, "message": message})
+}
+
+// handle wraps an endpoint handler with authorization and locking
+func (s *Server) handle(handler http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		s.requests++
+		w.Header().Set("Date", s.now().UTC().Format(http.TimeFormat))
+		if !s.authorize(r) {
+			writeError(w, http.StatusUnauthorized, "Unauthorized", "Invalid API credentials or signature")
+			return
+		}
+		handler(w, r)
+	}
+}
+
+func (s *Server) routes() http.Handler {
+	mux := http.NewServeMux()
+	route := func(pattern string, handler http.HandlerFunc) {
+		method, path, _ := strings.Cut(pattern, " ")
+		mux.HandleFunc(method+" /api/v3/"+path, s.handle(handler))
+	}
+
+	route("GET ping", s.ping)
+	route("GET regions", s.getRegions)
+	route("GET templates", s.getTemplates)
+	route("GET projects", s.getProjects)
+	route("GET projects/{projectId}", s.getProjectDetails)
+	route("GET projects/{projectId}/blueprints", s.getBlueprints)
+	route("GET projects/{projectId}/blueprints/{blueprintId}", s.getBlueprintDetails)
+	route("GET projects/{projectId}/policies", s.getPolicies)
+	route("POST policies", s.createPolicy)
+	route("GET envs", s.getEnvironments)
+	route("POST envs", s.createEnvironment)
+	route("GET envs/{envId}", s.getEnvironment)
+	route("DELETE envs/{envId}", s.deleteEnvironment)
+	route("GET envs/actions/getextended", s.getEnvironmentExtended)
+	route("PUT envs/actions/{action}", s.environmentAction)
+	route("PUT vms/actions/reboot", s.rebootVM)
+	route("PUT vms/actions/editvmhardware", s.editVMHardware)
+
+	mux.HandleFunc("/", s.handle(func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, http.StatusNotFound, "NotFound", "No such API endpoint: "+r.Method+" "+r.URL.Path)
+	}))
+	return mux
+}
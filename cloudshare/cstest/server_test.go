@@ -0,0 +1,165 @@
+package cstest_test
+
+import (
+	"context"
+	"errors"
+	"github.com/cloudshare/go-sdk/cloudshare"
+	"github.com/cloudshare/go-sdk/cloudshare/cstest"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func newServer(t *testing.T) *cstest.Server {
+	server := cstest.NewServer()
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestCatalog(t *testing.T) {
+	server := newServer(t)
+	c := server.Client()
+
+	regions := []cloudshare.Region{}
+	require.Nil(t, c.GetRegions(&regions))
+	require.Len(t, regions, 1)
+	assert.Equal(t, "Miami", regions[0].Name)
+
+	templates := []cloudshare.VMTemplate{}
+	require.Nil(t, c.GetTemplates(&cloudshare.GetTemplateParams{RegionID: regions[0].ID}, &templates))
+	require.Len(t, templates, 1)
+
+	projects := []cloudshare.Project{}
+	require.Nil(t, c.GetProjects(&projects))
+	require.Len(t, projects, 1)
+
+	details := cloudshare.ProjectDetails{}
+	require.Nil(t, c.GetProjectDetails(projects[0].ID, &details))
+	assert.Equal(t, projects[0].Name, details.Name)
+
+	blueprints := []cloudshare.Blueprint{}
+	require.Nil(t, c.GetBlueprints(projects[0].ID, &blueprints))
+	require.Len(t, blueprints, 1)
+
+	blueprint := cloudshare.BlueprintDetails{}
+	require.Nil(t, c.GetBlueprintDetails(projects[0].ID, blueprints[0].ID, &blueprint))
+	assert.Equal(t, blueprints[0].Name, blueprint.Name)
+
+	created := cloudshare.PolicyCreationResponse{}
+	require.Nil(t, c.CreateProjectPolicy(cloudshare.PolicyRequest{Name: "short", ProjectID: projects[0].ID}, &created))
+	policies := []cloudshare.Policy{}
+	require.Nil(t, c.GetPolicies(projects[0].ID, &policies))
+	assert.Len(t, policies, 2)
+}
+
+func TestRejectsBadSignature(t *testing.T) {
+	server := newServer(t)
+	c := server.Client()
+	c.APIKey = "wrong key"
+
+	regions := []cloudshare.Region{}
+	err := c.GetRegions(&regions)
+	assert.True(t, errors.Is(err, cloudshare.ErrUnauthorized))
+}
+
+func TestClockSkew(t *testing.T) {
+	server := newServer(t)
+	server.Now = func() time.Time { return time.Now().Add(10 * time.Minute) }
+	server.MaxClockSkew = 30 * time.Second
+	c := server.Client()
+
+	regions := []cloudshare.Region{}
+	require.Nil(t, c.GetRegions(&regions))
+	assert.Equal(t, 2, server.Requests(), "the first request is re-signed with the server's time")
+	assert.InDelta(t, 10*time.Minute, c.ClockOffset(), float64(5*time.Second))
+
+	require.Nil(t, c.GetRegions(&regions))
+	assert.Equal(t, 3, server.Requests())
+}
+
+func TestEnvironmentLifecycle(t *testing.T) {
+	server := newServer(t)
+	server.SettlePolls = 1
+	c := server.Client()
+
+	projects := []cloudshare.Project{}
+	require.Nil(t, c.GetProjects(&projects))
+	templates := []cloudshare.VMTemplate{}
+	require.Nil(t, c.GetTemplates(nil, &templates))
+
+	request := cloudshare.EnvironmentTemplateRequest{
+		Environment: cloudshare.Environment{Name: "env1", ProjectID: projects[0].ID, RegionID: templates[0].RegionID},
+		ItemsCart:   []cloudshare.VM{{Type: 2, Name: "vm1", TemplateVMID: templates[0].ID}},
+	}
+	response := cloudshare.CreateTemplateEnvResponse{}
+	require.Nil(t, c.EnvironmentCreateFromTemplate(&request, &response))
+	envID := response.EnvironmentID
+	require.NotEmpty(t, envID)
+
+	err := c.EnvironmentCreateFromTemplate(&request, &response)
+	assert.True(t, errors.Is(err, cloudshare.ErrConflict), "duplicate names are rejected")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	opts := &cloudshare.WaitOptions{Interval: time.Millisecond}
+	var statuses []cloudshare.EnvironmentStatusCode
+	opts.OnProgress = func(env *cloudshare.EnvironmentExtended) {
+		statuses = append(statuses, env.StatusCode)
+	}
+	env, err := c.WaitForReady(ctx, envID, opts)
+	require.Nil(t, err)
+	assert.Equal(t, []cloudshare.EnvironmentStatusCode{cloudshare.StatusPreparing, cloudshare.StatusReady}, statuses)
+	require.Len(t, env.Vms, 1)
+	assert.InDelta(t, 8*time.Hour, env.TimeUntilExpiration(), float64(time.Minute))
+	vmID := env.Vms[0].ID
+
+	require.Nil(t, c.RebootVM(vmID))
+	hw := cloudshare.EditVMHardwareResponse{}
+	require.Nil(t, c.EditVMHardware(cloudshare.EditVMHardwareRequest{VMID: vmID, NumCPUs: cloudshare.Ptr(4)}, &hw))
+	state, _ := server.Environment(envID)
+	assert.Equal(t, 4, state.Vms[0].CPUCount)
+
+	require.Nil(t, c.EnvironmentExtend(envID))
+	require.Nil(t, c.EnvironmentSuspend(envID))
+	err = c.EnvironmentSuspend(envID)
+	assert.True(t, errors.Is(err, cloudshare.ErrConflict), "can't suspend a stopping environment")
+	_, err = c.WaitForEnvironmentStatus(ctx, envID, cloudshare.StatusSuspended, opts)
+	require.Nil(t, err)
+
+	require.Nil(t, c.EnvironmentResume(envID))
+	_, err = c.WaitForReady(ctx, envID, opts)
+	require.Nil(t, err)
+
+	found, err := c.GetEnvironmentByName("env1")
+	require.Nil(t, err)
+	require.NotNil(t, found)
+	assert.Equal(t, envID, found.ID)
+
+	require.Nil(t, c.EnvironmentDelete(envID))
+	err = c.GetEnvironment(envID, "view", &cloudshare.Environment{})
+	assert.True(t, errors.Is(err, cloudshare.ErrNotFound))
+}
+
+func TestSimulatedCreationFailure(t *testing.T) {
+	server := newServer(t)
+	server.SettlePolls = 100
+	c := server.Client()
+
+	projects := []cloudshare.Project{}
+	require.Nil(t, c.GetProjects(&projects))
+	templates := []cloudshare.VMTemplate{}
+	require.Nil(t, c.GetTemplates(nil, &templates))
+	request := cloudshare.EnvironmentTemplateRequest{
+		Environment: cloudshare.Environment{Name: "env1", ProjectID: projects[0].ID},
+		ItemsCart:   []cloudshare.VM{{Name: "vm1", TemplateVMID: templates[0].ID}},
+	}
+	response := cloudshare.CreateTemplateEnvResponse{}
+	require.Nil(t, c.EnvironmentCreateFromTemplate(&request, &response))
+
+	server.SetEnvironmentStatus(response.EnvironmentID, cloudshare.StatusCreationFailed)
+	_, err := c.WaitForReady(context.Background(), response.EnvironmentID, &cloudshare.WaitOptions{Interval: time.Millisecond})
+	var statusErr *cloudshare.EnvironmentStatusError
+	require.True(t, errors.As(err, &statusErr))
+	assert.Equal(t, cloudshare.StatusCreationFailed, statusErr.StatusCode)
+}
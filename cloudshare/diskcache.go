@@ -0,0 +1,89 @@
+package cloudshare
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DiskCache is a CacheStore keeping one JSON file per entry in Dir, which is created as needed.
+// It lets cached responses outlive the process, e.g. across the runs of a tool.
+// It's best effort: entries that can't be read or written are treated as missing.
+// A ResponseCache indexes the entries of its DiskCache when first used, so it doesn't invalidate
+// the entries that other processes write to the same directory afterwards.
+type DiskCache struct {
+	Dir string
+}
+
+type diskCacheFile struct {
+	Key   string      `json:"key"`
+	Entry *CacheEntry `json:"entry"`
+}
+
+func (d DiskCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(d.Dir, hex.EncodeToString(sum[:])+".json")
+}
+
+func (d DiskCache) read(path string) (*diskCacheFile, bool) {
+	buffer, err := os.ReadFile(path)
+	if err != nil {
+		return nil, false
+	}
+	file := &diskCacheFile{}
+	if json.Unmarshal(buffer, file) != nil || file.Entry == nil {
+		return nil, false
+	}
+	return file, true
+}
+
+func (d DiskCache) Get(key string) (*CacheEntry, bool) {
+	file, ok := d.read(d.path(key))
+	if !ok || file.Key != key {
+		return nil, false
+	}
+	return file.Entry, true
+}
+
+func (d DiskCache) Set(key string, entry *CacheEntry) {
+	buffer, err := json.Marshal(diskCacheFile{Key: key, Entry: entry})
+	if err != nil || os.MkdirAll(d.Dir, 0o700) != nil {
+		return
+	}
+	// write then rename, so that readers never see a partial file
+	temp, err := os.CreateTemp(d.Dir, ".tmp-*")
+	if err != nil {
+		return
+	}
+	_, err = temp.Write(buffer)
+	if closeErr := temp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil || os.Rename(temp.Name(), d.path(key)) != nil {
+		os.Remove(temp.Name())
+	}
+}
+
+func (d DiskCache) Delete(key string) {
+	os.Remove(d.path(key))
+}
+
+func (d DiskCache) Keys() []string {
+	entries, err := os.ReadDir(d.Dir)
+	if err != nil {
+		return nil
+	}
+	keys := []string{}
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".json") {
+			continue
+		}
+		if file, ok := d.read(filepath.Join(d.Dir, entry.Name())); ok {
+			keys = append(keys, file.Key)
+		}
+	}
+	return keys
+}
@@ -1,16 +1,18 @@
 package cloudshare
 
+import "encoding/json"
+
 // Environment details
 type Environment struct {
-	ProjectID   string      `json:"projectId"`
-	TeamID      interface{} `json:"teamId"`
-	PolicyID    interface{} `json:"policyId"`
-	Description interface{} `json:"description"`
-	Status      string      `json:"status"`
-	OwnerEmail  string      `json:"ownerEmail"`
-	RegionID    string      `json:"regionId"`
-	Name        string      `json:"name"`
-	ID          string      `json:"id"`
+	ProjectID   ProjectID     `json:"projectId"`
+	TeamID      *string       `json:"teamId"`
+	PolicyID    *PolicyID     `json:"policyId"`
+	Description *string       `json:"description"`
+	Status      string        `json:"status"`
+	OwnerEmail  string        `json:"ownerEmail"`
+	RegionID    RegionID      `json:"regionId"`
+	Name        string        `json:"name"`
+	ID          EnvironmentID `json:"id"`
 }
 
 type Environments []Environment
@@ -18,23 +20,23 @@ type Environments []Environment
 type EnvironmentExtended struct {
 	Vms               []VMAccessDetails     `json:"vms"`
 	Description       string                `json:"description"`
-	BlueprintID       string                `json:"blueprintId"`
+	BlueprintID       BlueprintID           `json:"blueprintId"`
 	BlueprintName     string                `json:"blueprintName"`
-	PolicyID          string                `json:"policyId"`
+	PolicyID          PolicyID              `json:"policyId"`
 	PolicyName        string                `json:"policyName"`
-	ExpirationTime    string                `json:"expirationTime"`
+	ExpirationTime    Timestamp             `json:"expirationTime"`
 	InvitationAllowed bool                  `json:"invitationAllowed"`
-	Organization      interface{}           `json:"organization"`
+	Organization      *string               `json:"organization"`
 	OwnerEmail        string                `json:"ownerEmail"`
-	ProjectID         string                `json:"projectId"`
+	ProjectID         ProjectID             `json:"projectId"`
 	ProjectName       string                `json:"projectName"`
-	SnapshotID        interface{}           `json:"snapshotId"`
-	SnapshotName      interface{}           `json:"snapshotName"`
+	SnapshotID        *string               `json:"snapshotId"`
+	SnapshotName      *string               `json:"snapshotName"`
 	StatusCode        EnvironmentStatusCode `json:"statusCode"`
 	StatusText        string                `json:"statusText"`
-	RegionID          string                `json:"regionId"`
+	RegionID          RegionID              `json:"regionId"`
 	Name              string                `json:"name"`
-	ID                string                `json:"id"`
+	ID                EnvironmentID         `json:"id"`
 }
 
 type EnvironmentStatusCode int
@@ -55,56 +57,52 @@ const (
 )
 
 type VMAccessDetails struct {
-	ID                string      `json:"id"`
-	Name              string      `json:"name"`
-	Description       string      `json:"description"`
-	StatusText        string      `json:"statusText"`
-	Progress          int         `json:"progress"`
-	ImageID           string      `json:"imageId"`
-	Os                string      `json:"os"`
-	WebAccessURL      interface{} `json:"webAccessUrl"`
-	Fqdn              string      `json:"fqdn"`
-	ExternalAddress   string      `json:"externalAddress"`
-	InternalAddresses []string    `json:"internalAddresses"`
-	CPUCount          int         `json:"cpuCount"`
-	DiskSizeGB        int         `json:"diskSizeGb"`
-	MemorySizeMB      int         `json:"memorySizeMb"`
-	Username          string      `json:"username"`
-	Password          string      `json:"password"`
-	ConsoleToken      string      `json:"consoleToken"`
+	ID                VMID     `json:"id"`
+	Name              string   `json:"name"`
+	Description       string   `json:"description"`
+	StatusText        string   `json:"statusText"`
+	Progress          int      `json:"progress"`
+	ImageID           string   `json:"imageId"`
+	Os                string   `json:"os"`
+	WebAccessURL      *string  `json:"webAccessUrl"`
+	Fqdn              string   `json:"fqdn"`
+	ExternalAddress   string   `json:"externalAddress"`
+	InternalAddresses []string `json:"internalAddresses"`
+	CPUCount          int      `json:"cpuCount"`
+	DiskSizeGB        int      `json:"diskSizeGb"`
+	MemorySizeMB      int      `json:"memorySizeMb"`
+	Username          string   `json:"username"`
+	Password          string   `json:"password"`
+	ConsoleToken      string   `json:"consoleToken"`
 }
 
 // VMTemplate
 type VMTemplate struct {
-	Name                  string   `json:"name"`
-	Description           string   `json:"description"`
-	IsEnvironmentTemplate bool     `json:"isEnvironmentTemplate"`
-	Type                  int      `json:"type"`
-	ImageURL              string   `json:"imageUrl"`
-	RegionID              string   `json:"regionId"`
-	Tags                  []string `json:"tags"`
-	Categories            []string `json:"categories"`
-	Resources             struct {
-		CPUCount     int `json:"cpuCount"`
-		DiskSizeMB   int `json:"diskSizeMB"`
-		MemorySizeMB int `json:"memorySizeMB"`
-	} `json:"resources"`
-	NumberOfMachines                       int         `json:"numberOfMachines"`
-	HasMultipleVersions                    bool        `json:"hasMultipleVersions"`
-	HasDefaultVersion                      bool        `json:"hasDefaultVersion"`
-	DisabledForRegularEnvironmentCreation  interface{} `json:"disabledForRegularEnvironmentCreation"`
-	DisabledForTrainingEnvironmentCreation interface{} `json:"disabledForTrainingEnvironmentCreation"`
-	CanAddMultipleInstances                bool        `json:"canAddMultipleInstances"`
-	EnvTemplateScope                       interface{} `json:"envTemplateScope"`
-	CreationDate                           string      `json:"creationDate"`
-	ID                                     string      `json:"id"`
+	Name                                   string          `json:"name"`
+	Description                            string          `json:"description"`
+	IsEnvironmentTemplate                  bool            `json:"isEnvironmentTemplate"`
+	Type                                   int             `json:"type"`
+	ImageURL                               string          `json:"imageUrl"`
+	RegionID                               RegionID        `json:"regionId"`
+	Tags                                   []string        `json:"tags"`
+	Categories                             []string        `json:"categories"`
+	Resources                              Resources       `json:"resources"`
+	NumberOfMachines                       int             `json:"numberOfMachines"`
+	HasMultipleVersions                    bool            `json:"hasMultipleVersions"`
+	HasDefaultVersion                      bool            `json:"hasDefaultVersion"`
+	DisabledForRegularEnvironmentCreation  *bool           `json:"disabledForRegularEnvironmentCreation"`
+	DisabledForTrainingEnvironmentCreation *bool           `json:"disabledForTrainingEnvironmentCreation"`
+	CanAddMultipleInstances                bool            `json:"canAddMultipleInstances"`
+	EnvTemplateScope                       json.RawMessage `json:"envTemplateScope"`
+	CreationDate                           Timestamp       `json:"creationDate"`
+	ID                                     TemplateID      `json:"id"`
 }
 
 type VM struct {
-	Type         int         `json:"type"`
-	Name         string      `json:"name"`
-	Description  interface{} `json:"description"`
-	TemplateVMID string      `json:"templateVmId"`
+	Type         int        `json:"type"`
+	Name         string     `json:"name"`
+	Description  *string    `json:"description"`
+	TemplateVMID TemplateID `json:"templateVmId"`
 }
 
 type EnvironmentTemplateRequest struct {
@@ -113,49 +111,41 @@ type EnvironmentTemplateRequest struct {
 }
 
 type CreateTemplateEnvResponse struct {
-	Resources struct {
-		CPUCount     int `json:"cpuCount"`
-		DiskSizeMB   int `json:"diskSizeMB"`
-		MemorySizeMB int `json:"memorySizeMB"`
-	} `json:"resources"`
-	Vms []struct {
-		Name        string `json:"name"`
-		Description string `json:"description"`
-		OsTypeName  string `json:"osTypeName"`
-		ImageURL    string `json:"imageUrl"`
-		Resources   struct {
-			CPUCount     int `json:"cpuCount"`
-			DiskSizeMB   int `json:"diskSizeMB"`
-			MemorySizeMB int `json:"memorySizeMB"`
-		} `json:"resources"`
-		DomainName              interface{}   `json:"domainName"`
-		InternalIPs             []interface{} `json:"internalIPs"`
-		MacAddresses            []interface{} `json:"macAddresses"`
-		CanAddMultipleInstances bool          `json:"canAddMultipleInstances"`
-		HostName                string        `json:"hostName"`
-		VanityName              interface{}   `json:"vanityName"`
-		HTTPAccessEnabled       bool          `json:"httpAccessEnabled"`
-		StartWithHTTPS          bool          `json:"startWithHttps"`
-		User                    string        `json:"user"`
-		Password                string        `json:"password"`
-		ID                      string        `json:"id"`
+	Resources Resources `json:"resources"`
+	Vms       []struct {
+		Name                    string          `json:"name"`
+		Description             string          `json:"description"`
+		OsTypeName              string          `json:"osTypeName"`
+		ImageURL                string          `json:"imageUrl"`
+		Resources               Resources       `json:"resources"`
+		DomainName              *string         `json:"domainName"`
+		InternalIPs             json.RawMessage `json:"internalIPs"`
+		MacAddresses            json.RawMessage `json:"macAddresses"`
+		CanAddMultipleInstances bool            `json:"canAddMultipleInstances"`
+		HostName                string          `json:"hostName"`
+		VanityName              *string         `json:"vanityName"`
+		HTTPAccessEnabled       bool            `json:"httpAccessEnabled"`
+		StartWithHTTPS          bool            `json:"startWithHttps"`
+		User                    string          `json:"user"`
+		Password                string          `json:"password"`
+		ID                      VMID            `json:"id"`
 	} `json:"vms"`
-	EnvironmentID string `json:"environmentId"`
+	EnvironmentID EnvironmentID `json:"environmentId"`
 }
 
 /*
 	GetTemplateParams allows you to filter templates by various criteria:
 
-	projectID string (optional). "" means don't filter
-	regionID string (optional). "" means don't filter
+	projectID ProjectID (optional). "" means don't filter
+	regionID RegionID (optional). "" means don't filter
 	templateType string (optional). "0" = bluebrint, "1" = VM
 	skip int (default 0) - how many to skip.
 	take int (default 0) - how many to return. 0 = return all.
 */
 type GetTemplateParams struct {
 	TemplateType string
-	ProjectID    string
-	RegionID     string
+	ProjectID    ProjectID
+	RegionID     RegionID
 	Skip         int
 	Take         int
 }
@@ -0,0 +1,106 @@
+package cloudshare
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Sentinel errors that an *APIError can be matched against with errors.Is.
+//
+// Example:
+//
+//		err := client.EnvironmentSuspend(envID)
+//		if errors.Is(err, cloudshare.ErrNotFound) {
+//			...
+//		}
+var (
+	ErrBadRequest    = errors.New("cloudshare: bad request")
+	ErrUnauthorized  = errors.New("cloudshare: unauthorized")
+	ErrForbidden     = errors.New("cloudshare: forbidden")
+	ErrNotFound      = errors.New("cloudshare: not found")
+	ErrConflict      = errors.New("cloudshare: conflict")
+	ErrQuotaExceeded = errors.New("cloudshare: quota exceeded")
+	ErrRateLimited   = errors.New("cloudshare: rate limited")
+	ErrServer        = errors.New("cloudshare: server error")
+	ErrClockSkew     = errors.New("cloudshare: clock skew")
+)
+
+// APIError is returned by client.Request, and by all the typed API functions, in case of a failure.
+// It is always returned as a *APIError, so it can be extracted with errors.As.
+//
+// Code and Message are parsed from the API's error response.
+// StatusCode is 0 when no HTTP response was received (e.g. network failures),
+// in which case InnerError holds the cause.
+type APIError struct {
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+	InnerError error  `json:"-"`
+	StatusCode int    `json:"-"`
+	Method     string `json:"-"`
+	Path       string `json:"-"`
+	// Body is the raw HTTP response body, if any
+	Body []byte `json:"-"`
+	// Retryable is true when the failure is transient and the call may succeed if repeated.
+	// For error responses, it's decided by the client's RetryPolicy.RetryableStatus.
+	Retryable bool `json:"-"`
+	// ClockSkew is set when authentication failed because the request's timestamp was that far
+	// behind the server's clock (negative when ahead)
+	ClockSkew time.Duration `json:"-"`
+}
+
+func (e APIError) Error() string {
+	s := e.Message
+	if s == "" && e.StatusCode != 0 {
+		s = fmt.Sprintf("%s %s: %d %s", e.Method, e.Path, e.StatusCode, http.StatusText(e.StatusCode))
+	}
+	if e.ClockSkew != 0 {
+		s += fmt.Sprintf(" (the local clock is off by %s)", e.ClockSkew.Round(time.Second))
+	}
+	if e.InnerError != nil {
+		s += "\n" + e.InnerError.Error()
+	}
+	return s
+}
+
+// Unwrap returns the underlying error, e.g. a network error or context.Canceled
+func (e APIError) Unwrap() error {
+	return e.InnerError
+}
+
+// Is reports whether the error matches one of the sentinel errors, based on the HTTP status
+func (e APIError) Is(target error) bool {
+	switch target {
+	case ErrBadRequest:
+		return e.StatusCode == http.StatusBadRequest
+	case ErrUnauthorized:
+		return e.StatusCode == http.StatusUnauthorized
+	case ErrForbidden:
+		return e.StatusCode == http.StatusForbidden
+	case ErrNotFound:
+		return e.StatusCode == http.StatusNotFound
+	case ErrConflict:
+		return e.StatusCode == http.StatusConflict
+	case ErrQuotaExceeded:
+		return e.isQuotaExceeded()
+	case ErrRateLimited:
+		return e.StatusCode == http.StatusTooManyRequests
+	case ErrServer:
+		return e.StatusCode/100 == 5
+	case ErrClockSkew:
+		return e.ClockSkew != 0
+	}
+	return false
+}
+
+// isQuotaExceeded detects quota failures, which the API reports as a client
+// error with a quota related code or message rather than a dedicated status.
+func (e APIError) isQuotaExceeded() bool {
+	if e.StatusCode/100 != 4 {
+		return false
+	}
+	return strings.Contains(strings.ToLower(e.Code), "quota") ||
+		strings.Contains(strings.ToLower(e.Message), "quota")
+}
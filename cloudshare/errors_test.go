@@ -0,0 +1,84 @@
+package cloudshare
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+)
+
+func TestAPIErrorFromHTTPFailure(t *testing.T) {
+	c := newLocalClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"code": "0x20001", "message": "Environment not found"}`))
+	})
+
+	err := c.EnvironmentSuspend("ENabc")
+	var apiErr *APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusNotFound, apiErr.StatusCode)
+	assert.Equal(t, "PUT", apiErr.Method)
+	assert.Equal(t, "envs/actions/suspend", apiErr.Path)
+	assert.Equal(t, "0x20001", apiErr.Code)
+	assert.Equal(t, "Environment not found", apiErr.Message)
+	assert.Contains(t, string(apiErr.Body), "Environment not found")
+	assert.False(t, apiErr.Retryable)
+	assert.True(t, errors.Is(err, ErrNotFound))
+	assert.False(t, errors.Is(err, ErrConflict))
+}
+
+func TestAPIErrorFromTransportFailure(t *testing.T) {
+	c := newLocalClient(t, func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	})
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	regions := []Region{}
+	err := c.GetRegionsWithContext(ctx, &regions)
+	var apiErr *APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, 0, apiErr.StatusCode)
+	assert.Equal(t, "regions", apiErr.Path)
+	assert.False(t, apiErr.Retryable)
+	assert.True(t, errors.Is(err, context.Canceled))
+}
+
+func TestAPIErrorFromBadJSON(t *testing.T) {
+	c := newLocalClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`not json`))
+	})
+	regions := []Region{}
+	err := c.GetRegions(&regions)
+	var apiErr *APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.Equal(t, http.StatusOK, apiErr.StatusCode)
+	assert.Equal(t, "not json", string(apiErr.Body))
+}
+
+func TestAPIErrorSentinels(t *testing.T) {
+	cases := []struct {
+		err      *APIError
+		sentinel error
+	}{
+		{&APIError{StatusCode: 400}, ErrBadRequest},
+		{&APIError{StatusCode: 401}, ErrUnauthorized},
+		{&APIError{StatusCode: 403}, ErrForbidden},
+		{&APIError{StatusCode: 404}, ErrNotFound},
+		{&APIError{StatusCode: 409}, ErrConflict},
+		{&APIError{StatusCode: 400, Message: "Project CPU quota exceeded"}, ErrQuotaExceeded},
+		{&APIError{StatusCode: 429}, ErrRateLimited},
+		{&APIError{StatusCode: 503}, ErrServer},
+	}
+	for _, c := range cases {
+		assert.True(t, errors.Is(c.err, c.sentinel), "%d should match %s", c.err.StatusCode, c.sentinel)
+	}
+	assert.False(t, errors.Is(&APIError{StatusCode: 500, Message: "quota"}, ErrQuotaExceeded))
+}
+
+func TestAPIErrorMessage(t *testing.T) {
+	assert.Equal(t, "GET regions: 502 Bad Gateway", (&APIError{StatusCode: 502, Method: "GET", Path: "regions"}).Error())
+	assert.Equal(t, "failed\ncause", (&APIError{Message: "failed", InnerError: errors.New("cause")}).Error())
+}
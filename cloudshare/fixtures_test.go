@@ -0,0 +1,83 @@
+package cloudshare
+
+import (
+	"bytes"
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// fixtures maps API responses in testdata to the types they decode into.
+// Every field of a fixture must be modeled, and must re-encode to the same JSON.
+//
+// The fixtures are synthetic: they were written by hand after the API documentation and the fields
+// the SDK already modeled, not captured from the live API, and their values are made up.
+// Replace them with captured responses (with secrets redacted) when possible.
+var fixtures = map[string]func() interface{}{
+	"envs.json":              func() interface{} { return &Environments{} },
+	"envs_getextended.json":  func() interface{} { return &EnvironmentExtended{} },
+	"envs_create.json":       func() interface{} { return &CreateTemplateEnvResponse{} },
+	"templates.json":         func() interface{} { return &[]VMTemplate{} },
+	"projects.json":          func() interface{} { return &[]Project{} },
+	"project_details.json":   func() interface{} { return &ProjectDetails{} },
+	"blueprints.json":        func() interface{} { return &[]Blueprint{} },
+	"blueprint_details.json": func() interface{} { return &BlueprintDetails{} },
+	"policies.json":          func() interface{} { return &[]Policy{} },
+	"regions.json":           func() interface{} { return &[]Region{} },
+	"editvmhardware.json":    func() interface{} { return &EditVMHardwareResponse{} },
+}
+
+func loadFixture(t *testing.T, name string, ret interface{}) []byte {
+	buffer, err := os.ReadFile(filepath.Join("testdata", name))
+	require.NoError(t, err)
+	decoder := json.NewDecoder(bytes.NewReader(buffer))
+	decoder.DisallowUnknownFields()
+	require.NoError(t, decoder.Decode(ret), name)
+	return buffer
+}
+
+func TestFixturesRoundTrip(t *testing.T) {
+	files, err := filepath.Glob(filepath.Join("testdata", "*.json"))
+	require.NoError(t, err)
+	require.Len(t, files, len(fixtures), "every fixture needs a type")
+
+	for name, newValue := range fixtures {
+		t.Run(name, func(t *testing.T) {
+			value := newValue()
+			original := loadFixture(t, name, value)
+			encoded, err := json.Marshal(value)
+			require.NoError(t, err)
+			assert.JSONEq(t, string(original), string(encoded))
+		})
+	}
+}
+
+func TestNullableFields(t *testing.T) {
+	envs := Environments{}
+	loadFixture(t, "envs.json", &envs)
+	assert.Nil(t, envs[0].TeamID)
+	assert.Nil(t, envs[0].PolicyID)
+	assert.Nil(t, envs[0].Description)
+	require.NotNil(t, envs[1].PolicyID)
+	assert.Equal(t, PolicyID("PO2mV9c4Jx7RtY1uH5eK8bN3"), *envs[1].PolicyID)
+	assert.Equal(t, "Nightly regression environment", *envs[1].Description)
+
+	env := EnvironmentExtended{}
+	loadFixture(t, "envs_getextended.json", &env)
+	assert.Equal(t, "SN5tR4eW3qA2sD1fG0hJ9kL8", *env.SnapshotID)
+	assert.Nil(t, env.Organization)
+	require.NotNil(t, env.Vms[0].WebAccessURL)
+	assert.Nil(t, env.Vms[1].WebAccessURL)
+
+	details := ProjectDetails{}
+	loadFixture(t, "project_details.json", &details)
+	assert.Equal(t, 64, *details.ProjectResourceQuota.CPUCount)
+	assert.Nil(t, details.ProjectResourceQuota.MemorySizeMB, "no quota")
+
+	buffer, err := json.Marshal(EditVMHardwareRequest{VMID: "MCabc", NumCPUs: Ptr(4)})
+	require.NoError(t, err)
+	assert.JSONEq(t, `{"vmId": "MCabc", "numCpus": 4, "memorySizeMBs": null, "diskSizeGBs": null}`, string(buffer))
+}
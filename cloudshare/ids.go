@@ -0,0 +1,135 @@
+package cloudshare
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// The ID types below let the compiler catch mixed up IDs. They're validated when parsed, and when
+// passed to the API wrappers. Environment and region IDs are checked for their known prefixes, "EN"
+// (see EnvIDToURL) and "RE"; the prefixes of other IDs aren't documented, so those are only required
+// to be non-empty. Decoding API responses doesn't validate IDs, so that an ID in an unexpected format
+// doesn't break decoding a whole response.
+
+// ErrInvalidID is wrapped by all ID validation errors
+var ErrInvalidID = errors.New("cloudshare: invalid ID")
+
+var idPattern = regexp.MustCompile(`^[A-Z]{2}[A-Za-z0-9_-]+$`)
+
+// validateID checks that id is non-empty and, if prefix is set, that it's a well formed ID starting with prefix
+func validateID(kind string, id string, prefix string) error {
+	if id == "" {
+		return fmt.Errorf("%w: empty %s ID", ErrInvalidID, kind)
+	}
+	if prefix != "" && !(idPattern.MatchString(id) && strings.HasPrefix(id, prefix)) {
+		return fmt.Errorf("%w: %q is not a %s ID (expecting %s...)", ErrInvalidID, id, kind, prefix)
+	}
+	return nil
+}
+
+// EnvironmentID identifies an environment, e.g. "ENq1w2e3r4t5y6u7i8o9p0"
+type EnvironmentID string
+
+// ParseEnvironmentID validates an environment ID
+func ParseEnvironmentID(s string) (EnvironmentID, error) {
+	return EnvironmentID(s), EnvironmentID(s).Validate()
+}
+
+// Validate returns an error wrapping ErrInvalidID if the ID is malformed
+func (id EnvironmentID) Validate() error {
+	return validateID("environment", string(id), "EN")
+}
+
+// VMID identifies a VM (machine) in an environment
+type VMID string
+
+// ParseVMID validates a VM ID
+func ParseVMID(s string) (VMID, error) {
+	return VMID(s), VMID(s).Validate()
+}
+
+// Validate returns an error wrapping ErrInvalidID if the ID is malformed
+func (id VMID) Validate() error {
+	return validateID("VM", string(id), "")
+}
+
+// ProjectID identifies a project
+type ProjectID string
+
+// ParseProjectID validates a project ID
+func ParseProjectID(s string) (ProjectID, error) {
+	return ProjectID(s), ProjectID(s).Validate()
+}
+
+// Validate returns an error wrapping ErrInvalidID if the ID is malformed
+func (id ProjectID) Validate() error {
+	return validateID("project", string(id), "")
+}
+
+// BlueprintID identifies a blueprint
+type BlueprintID string
+
+// ParseBlueprintID validates a blueprint ID
+func ParseBlueprintID(s string) (BlueprintID, error) {
+	return BlueprintID(s), BlueprintID(s).Validate()
+}
+
+// Validate returns an error wrapping ErrInvalidID if the ID is malformed
+func (id BlueprintID) Validate() error {
+	return validateID("blueprint", string(id), "")
+}
+
+// PolicyID identifies a policy
+type PolicyID string
+
+// ParsePolicyID validates a policy ID
+func ParsePolicyID(s string) (PolicyID, error) {
+	return PolicyID(s), PolicyID(s).Validate()
+}
+
+// Validate returns an error wrapping ErrInvalidID if the ID is malformed
+func (id PolicyID) Validate() error {
+	return validateID("policy", string(id), "")
+}
+
+// RegionID identifies a region, e.g. "REKolD1-ab84YIxODeMGob9A2"
+type RegionID string
+
+// ParseRegionID validates a region ID
+func ParseRegionID(s string) (RegionID, error) {
+	return RegionID(s), RegionID(s).Validate()
+}
+
+// Validate returns an error wrapping ErrInvalidID if the ID is malformed
+func (id RegionID) Validate() error {
+	return validateID("region", string(id), "RE")
+}
+
+// TemplateID identifies a VM template or an environment template, i.e. a blueprint
+type TemplateID string
+
+// ParseTemplateID validates a template ID
+func ParseTemplateID(s string) (TemplateID, error) {
+	return TemplateID(s), TemplateID(s).Validate()
+}
+
+// Validate returns an error wrapping ErrInvalidID if the ID is malformed
+func (id TemplateID) Validate() error {
+	return validateID("template", string(id), "")
+}
+
+type validator interface {
+	Validate() error
+}
+
+// validateIDs returns the first validation error of ids
+func validateIDs(ids ...validator) error {
+	for _, id := range ids {
+		if err := id.Validate(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,65 @@
+package cloudshare
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+)
+
+func TestParseIDs(t *testing.T) {
+	envID, err := ParseEnvironmentID("ENq1w2e3r4")
+	require.NoError(t, err)
+	assert.Equal(t, EnvironmentID("ENq1w2e3r4"), envID)
+
+	_, err = ParseEnvironmentID("MCq1w2e3r4")
+	assert.True(t, errors.Is(err, ErrInvalidID))
+	assert.Contains(t, err.Error(), "environment")
+
+	for _, invalid := range []string{"", "E", "EN", "en123", "EN12/34", "EN 12"} {
+		_, err := ParseEnvironmentID(invalid)
+		assert.True(t, errors.Is(err, ErrInvalidID), "%q should be rejected", invalid)
+	}
+
+	_, err = ParseRegionID("REKolD1-ab84YIxODeMGob9A2")
+	assert.NoError(t, err)
+	_, err = ParseRegionID("ENabc")
+	assert.True(t, errors.Is(err, ErrInvalidID))
+
+	_, err = ParseVMID("MCabc")
+	assert.NoError(t, err)
+	_, err = ParseTemplateID("ENabc")
+	assert.NoError(t, err, "prefixes of other IDs aren't checked")
+	for _, err := range []error{ProjectID("").Validate(), BlueprintID("").Validate(), PolicyID("").Validate(), VMID("").Validate(), TemplateID("").Validate()} {
+		assert.True(t, errors.Is(err, ErrInvalidID))
+	}
+}
+
+func TestIDsJSON(t *testing.T) {
+	env := EnvironmentExtended{}
+	require.NoError(t, json.Unmarshal([]byte(`{"id": "ENabc", "projectId": "PRabc", "policyId": "", "regionId": "REabc"}`), &env))
+	assert.Equal(t, EnvironmentID("ENabc"), env.ID)
+	assert.Equal(t, ProjectID("PRabc"), env.ProjectID)
+	assert.Equal(t, PolicyID(""), env.PolicyID)
+
+	buffer, err := json.Marshal(Policy{ID: "POabc", ProjectID: "PRabc"})
+	require.NoError(t, err)
+	assert.Contains(t, string(buffer), `"projectId":"PRabc","allowEnvironmentCreation":false,"id":"POabc"`)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"id": "env-1", "projectId": "12"}`), &env), "decoding doesn't validate IDs")
+	assert.Equal(t, EnvironmentID("env-1"), env.ID)
+	assert.Error(t, env.ID.Validate())
+}
+
+func TestWrappersValidateIDs(t *testing.T) {
+	c := newLocalClient(t, func(w http.ResponseWriter, r *http.Request) {
+		t.Errorf("unexpected request %s %s", r.Method, r.URL)
+	})
+
+	assert.True(t, errors.Is(c.EnvironmentSuspend("MCabc"), ErrInvalidID))
+	assert.True(t, errors.Is(c.RebootVM(""), ErrInvalidID))
+	assert.True(t, errors.Is(c.GetBlueprintDetails("PRabc", "", &BlueprintDetails{}), ErrInvalidID))
+	assert.True(t, errors.Is(c.EditVMHardware(EditVMHardwareRequest{}, &EditVMHardwareResponse{}), ErrInvalidID))
+}
@@ -0,0 +1,64 @@
+// Package redact hides the values of sensitive JSON fields, for the request logs of package cloudshare
+// and the cassettes of package cassette.
+package redact
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+)
+
+// Redacted replaces the redacted values
+const Redacted = "REDACTED"
+
+// DefaultFields are the JSON field names redacted by default: the VM credentials returned by the API,
+// and API keys
+var DefaultFields = []string{"password", "consoleToken", "apiKey"}
+
+func isRedacted(name string, fields []string) bool {
+	for _, field := range fields {
+		if strings.EqualFold(field, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func value(v interface{}, fields []string) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if isRedacted(key, fields) {
+				if item != nil {
+					v[key] = Redacted
+				}
+			} else {
+				v[key] = value(item, fields)
+			}
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = value(v[i], fields)
+		}
+	}
+	return v
+}
+
+// JSON replaces the values of fields (case insensitive, at any depth) in a JSON body.
+// Bodies that aren't valid JSON are returned as is.
+func JSON(body []byte, fields []string) string {
+	if len(body) == 0 {
+		return ""
+	}
+	var parsed interface{}
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	decoder.UseNumber()
+	if err := decoder.Decode(&parsed); err != nil {
+		return string(body)
+	}
+	buffer, err := json.Marshal(value(parsed, fields))
+	if err != nil {
+		return string(body)
+	}
+	return string(buffer)
+}
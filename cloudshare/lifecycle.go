@@ -0,0 +1,217 @@
+package cloudshare
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+var statusNames = map[EnvironmentStatusCode]string{
+	StatusFutureAllocationScheduled: "FutureAllocationScheduled",
+	StatusAllocationScheduledNoRun:  "AllocationScheduledNoRun",
+	StatusReady:                     "Ready",
+	StatusSuspended:                 "Suspended",
+	StatusArchived:                  "Archived",
+	StatusDeleted:                   "Deleted",
+	StatusUnknown:                   "Unknown",
+	StatusPublishing:                "Publishing",
+	StatusPreparing:                 "Preparing",
+	StatusCreationFailed:            "CreationFailed",
+	StatusInGrace:                   "InGrace",
+	StatusStopping:                  "Stopping",
+}
+
+// String returns the status name, e.g. "Ready"
+func (s EnvironmentStatusCode) String() string {
+	if name, ok := statusNames[s]; ok {
+		return name
+	}
+	return "EnvironmentStatusCode(" + strconv.Itoa(int(s)) + ")"
+}
+
+// ParseEnvironmentStatus parses a status name (as returned by String) or a numeric status code
+func ParseEnvironmentStatus(text string) (EnvironmentStatusCode, error) {
+	for code, name := range statusNames {
+		if name == text {
+			return code, nil
+		}
+	}
+	if n, err := strconv.Atoi(text); err == nil {
+		return EnvironmentStatusCode(n), nil
+	}
+	return StatusUnknown, fmt.Errorf("unknown environment status %q", text)
+}
+
+// MarshalText encodes the status as its name
+func (s EnvironmentStatusCode) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// UnmarshalText decodes a status name or a numeric status code
+func (s *EnvironmentStatusCode) UnmarshalText(text []byte) error {
+	code, err := ParseEnvironmentStatus(string(text))
+	if err != nil {
+		return err
+	}
+	*s = code
+	return nil
+}
+
+// MarshalJSON encodes the status as a number, like the API does
+func (s EnvironmentStatusCode) MarshalJSON() ([]byte, error) {
+	return []byte(strconv.Itoa(int(s))), nil
+}
+
+// UnmarshalJSON decodes either a numeric status code or a quoted status name
+func (s *EnvironmentStatusCode) UnmarshalJSON(data []byte) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err == nil {
+		return s.UnmarshalText([]byte(text))
+	}
+	var n int
+	if err := json.Unmarshal(data, &n); err != nil {
+		return fmt.Errorf("invalid environment status %s", data)
+	}
+	*s = EnvironmentStatusCode(n)
+	return nil
+}
+
+// IsTerminal returns true for statuses an environment never leaves
+func (s EnvironmentStatusCode) IsTerminal() bool {
+	return s == StatusDeleted || s == StatusCreationFailed
+}
+
+// IsTransitional returns true for statuses the environment leaves on its own,
+// e.g. while it's being prepared or stopped
+func (s EnvironmentStatusCode) IsTransitional() bool {
+	switch s {
+	case StatusFutureAllocationScheduled, StatusAllocationScheduledNoRun, StatusPreparing, StatusPublishing, StatusStopping:
+		return true
+	}
+	return false
+}
+
+// transitions lists the statuses an environment can move to from each status.
+// The API doesn't document these; they're inferred from observed behavior, so the checks built on them are advisory.
+var transitions = map[EnvironmentStatusCode][]EnvironmentStatusCode{
+	StatusFutureAllocationScheduled: {StatusPreparing, StatusDeleted},
+	StatusAllocationScheduledNoRun:  {StatusPreparing, StatusDeleted},
+	StatusPreparing:                 {StatusReady, StatusCreationFailed, StatusDeleted},
+	StatusReady:                     {StatusStopping, StatusSuspended, StatusInGrace, StatusPublishing, StatusDeleted},
+	StatusInGrace:                   {StatusReady, StatusStopping, StatusSuspended, StatusDeleted},
+	StatusStopping:                  {StatusSuspended, StatusDeleted},
+	StatusPublishing:                {StatusReady, StatusSuspended, StatusDeleted},
+	StatusSuspended:                 {StatusPreparing, StatusReady, StatusArchived, StatusDeleted},
+	StatusArchived:                  {StatusDeleted},
+	StatusDeleted:                   {},
+	StatusCreationFailed:            {StatusDeleted},
+}
+
+// CanTransitionTo returns true if an environment in status s can move to status to.
+// Transitions from StatusUnknown are always allowed.
+func (s EnvironmentStatusCode) CanTransitionTo(to EnvironmentStatusCode) bool {
+	if s == StatusUnknown || s == to {
+		return true
+	}
+	for _, allowed := range transitions[s] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// CanReach returns true if an environment in status s can eventually move to status to,
+// through any number of transitions. Statuses missing from the transition graph, such as
+// StatusUnknown, may reach any status. The graph is inferred rather than documented, so treat
+// the result as a hint; WaitForEnvironmentStatus only gives up on terminal statuses.
+func (s EnvironmentStatusCode) CanReach(to EnvironmentStatusCode) bool {
+	seen := map[EnvironmentStatusCode]bool{s: true}
+	queue := []EnvironmentStatusCode{s}
+	for len(queue) > 0 {
+		status := queue[0]
+		queue = queue[1:]
+		if status == to {
+			return true
+		}
+		next, known := transitions[status]
+		if !known {
+			return true
+		}
+		for _, n := range next {
+			if !seen[n] {
+				seen[n] = true
+				queue = append(queue, n)
+			}
+		}
+	}
+	return false
+}
+
+// EnvironmentAction is an action that changes an environment's status
+type EnvironmentAction string
+
+const (
+	ActionSuspend  EnvironmentAction = "suspend"
+	ActionResume   EnvironmentAction = "resume"
+	ActionExtend   EnvironmentAction = "extend"
+	ActionPostpone EnvironmentAction = "postpone"
+)
+
+// actionSources lists the statuses each action can be applied in
+var actionSources = map[EnvironmentAction][]EnvironmentStatusCode{
+	ActionSuspend:  {StatusReady, StatusInGrace},
+	ActionResume:   {StatusSuspended},
+	ActionExtend:   {StatusReady, StatusInGrace, StatusSuspended},
+	ActionPostpone: {StatusReady, StatusInGrace},
+}
+
+// Allows returns true if the action can be applied to an environment in the given status
+func (a EnvironmentAction) Allows(status EnvironmentStatusCode) bool {
+	if status == StatusUnknown {
+		return true
+	}
+	for _, allowed := range actionSources[a] {
+		if allowed == status {
+			return true
+		}
+	}
+	return false
+}
+
+// ErrInvalidTransition matches every *TransitionError with errors.Is
+var ErrInvalidTransition = errors.New("cloudshare: invalid environment status transition")
+
+// TransitionError is returned when an action doesn't make sense in the environment's current status,
+// e.g. resuming an archived environment.
+type TransitionError struct {
+	EnvID  EnvironmentID
+	Action EnvironmentAction
+	Status EnvironmentStatusCode
+}
+
+func (e *TransitionError) Error() string {
+	return fmt.Sprintf("cannot %s environment %s in status %s", e.Action, e.EnvID, e.Status)
+}
+
+func (e *TransitionError) Is(target error) bool {
+	return target == ErrInvalidTransition
+}
+
+// validateAction fetches the environment's status and checks that the action can be applied to it.
+// It does nothing unless Client.ValidateTransitions is set, as it costs an extra request per action.
+func (c *Client) validateAction(ctx context.Context, action EnvironmentAction, envID EnvironmentID) error {
+	if !c.ValidateTransitions {
+		return nil
+	}
+	env := EnvironmentExtended{}
+	if err := c.GetEnvironmentExtendedWithContext(ctx, envID, &env); err != nil {
+		return err
+	}
+	if !action.Allows(env.StatusCode) {
+		return &TransitionError{EnvID: envID, Action: action, Status: env.StatusCode}
+	}
+	return nil
+}
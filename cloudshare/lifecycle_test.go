@@ -0,0 +1,96 @@
+package cloudshare
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+)
+
+func TestEnvironmentStatusNames(t *testing.T) {
+	assert.Equal(t, "Ready", StatusReady.String())
+	assert.Equal(t, "CreationFailed", StatusCreationFailed.String())
+	assert.Equal(t, "EnvironmentStatusCode(42)", EnvironmentStatusCode(42).String())
+
+	for code := StatusFutureAllocationScheduled; code <= StatusStopping; code++ {
+		parsed, err := ParseEnvironmentStatus(code.String())
+		require.NoError(t, err)
+		assert.Equal(t, code, parsed)
+	}
+	_, err := ParseEnvironmentStatus("Sleeping")
+	assert.Error(t, err)
+}
+
+func TestEnvironmentStatusJSON(t *testing.T) {
+	env := EnvironmentExtended{}
+	require.NoError(t, json.Unmarshal([]byte(`{"statusCode": 3}`), &env))
+	assert.Equal(t, StatusSuspended, env.StatusCode)
+
+	require.NoError(t, json.Unmarshal([]byte(`{"statusCode": "Archived"}`), &env))
+	assert.Equal(t, StatusArchived, env.StatusCode)
+
+	buffer, err := json.Marshal(env)
+	require.NoError(t, err)
+	assert.Contains(t, string(buffer), `"statusCode":4`)
+
+	text, err := StatusInGrace.MarshalText()
+	require.NoError(t, err)
+	assert.Equal(t, "InGrace", string(text))
+
+	byStatus := map[EnvironmentStatusCode]int{StatusReady: 1}
+	buffer, err = json.Marshal(byStatus)
+	require.NoError(t, err)
+	assert.Equal(t, `{"Ready":1}`, string(buffer))
+}
+
+func TestEnvironmentStatusPredicates(t *testing.T) {
+	assert.True(t, StatusDeleted.IsTerminal())
+	assert.True(t, StatusCreationFailed.IsTerminal())
+	assert.False(t, StatusSuspended.IsTerminal())
+	assert.True(t, StatusPreparing.IsTransitional())
+	assert.True(t, StatusStopping.IsTransitional())
+	assert.False(t, StatusReady.IsTransitional())
+
+	assert.True(t, StatusReady.CanTransitionTo(StatusSuspended))
+	assert.True(t, StatusSuspended.CanTransitionTo(StatusReady))
+	assert.False(t, StatusArchived.CanTransitionTo(StatusReady))
+	assert.False(t, StatusDeleted.CanTransitionTo(StatusReady))
+
+	assert.True(t, StatusStopping.CanReach(StatusReady), "through Suspended")
+	assert.True(t, StatusArchived.CanReach(StatusDeleted))
+	assert.False(t, StatusArchived.CanReach(StatusReady))
+	assert.False(t, StatusCreationFailed.CanReach(StatusSuspended))
+	assert.True(t, StatusUnknown.CanReach(StatusReady))
+
+	assert.True(t, ActionResume.Allows(StatusSuspended))
+	assert.False(t, ActionResume.Allows(StatusArchived))
+	assert.False(t, ActionSuspend.Allows(StatusSuspended))
+	assert.True(t, ActionExtend.Allows(StatusReady))
+}
+
+func TestValidateTransitions(t *testing.T) {
+	var actions []string
+	c := newLocalClient(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/api/v3/envs/actions/getextended" {
+			fmt.Fprintf(w, `{"id": "ENabc", "statusCode": %d}`, StatusArchived)
+			return
+		}
+		actions = append(actions, r.URL.Path)
+	})
+
+	require.Nil(t, c.EnvironmentResume("ENabc"), "no validation unless enabled")
+	require.Len(t, actions, 1)
+
+	c.ValidateTransitions = true
+	err := c.EnvironmentResume("ENabc")
+	var transitionErr *TransitionError
+	require.True(t, errors.As(err, &transitionErr))
+	assert.Equal(t, StatusArchived, transitionErr.Status)
+	assert.Equal(t, ActionResume, transitionErr.Action)
+	assert.True(t, errors.Is(err, ErrInvalidTransition))
+	assert.Equal(t, "cannot resume environment ENabc in status Archived", err.Error())
+	assert.Len(t, actions, 1, "invalid action should not be sent")
+}
@@ -0,0 +1,49 @@
+package cloudshare
+
+import (
+	"net/url"
+)
+
+// Links builds URLs of pages in the CloudShare web UI. The web UI is assumed to be served
+// from the same scheme, host and path prefix as the API, i.e. the client's base URL without
+// its "api/v3/" suffix.
+//
+// Only pages whose URLs are known are covered: the environment page comes from EnvIDToURL,
+// and the user details page from the README. Project, blueprint and VM console
+// pages aren't, since their URLs aren't documented and haven't been checked against the web UI.
+//
+// Example:
+//
+//		links, err := client.Links()
+//		envURL, err := links.Environment(env.ID)
+type Links struct {
+	root *url.URL
+}
+
+// Links returns a link builder for the web UI matching the client's base URL
+func (c *Client) Links() (*Links, error) {
+	root, err := c.webRoot()
+	if err != nil {
+		return nil, err
+	}
+	return &Links{root: root}, nil
+}
+
+func (l *Links) page(path string) string {
+	u := *l.root
+	u.Path += path
+	return u.String()
+}
+
+// Environment returns the URL of an environment's page, the one EnvIDToURL returns on use.cloudshare.com
+func (l *Links) Environment(envID EnvironmentID) (string, error) {
+	if err := envID.Validate(); err != nil {
+		return "", err
+	}
+	return l.page("Ent/Environment.mvc/View/" + string(envID)[2:]), nil
+}
+
+// UserDetails returns the URL of the user details page, where API keys are managed
+func (l *Links) UserDetails() string {
+	return l.page("Ent/Vendor/UserDetails.aspx")
+}
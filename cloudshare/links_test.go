@@ -0,0 +1,45 @@
+package cloudshare
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestLinks(t *testing.T) {
+	c := &Client{APIHost: "staging.cloudshare.com"}
+	links, err := c.Links()
+	require.NoError(t, err)
+
+	envURL, err := links.Environment("ENq1w2e3r4")
+	require.NoError(t, err)
+	assert.Equal(t, "https://staging.cloudshare.com/Ent/Environment.mvc/View/q1w2e3r4", envURL)
+
+	assert.Equal(t, "https://staging.cloudshare.com/Ent/Vendor/UserDetails.aspx", links.UserDetails())
+}
+
+func TestLinksWithPathPrefix(t *testing.T) {
+	c := &Client{BaseURL: "http://gateway.local:8080/cs/api/v3/"}
+	envURL, err := c.EnvIDToURL("ENabc")
+	require.NoError(t, err)
+	assert.Equal(t, "http://gateway.local:8080/cs/Ent/Environment.mvc/View/abc", envURL)
+}
+
+func TestLinksInvalidIDs(t *testing.T) {
+	links, err := (&Client{}).Links()
+	require.NoError(t, err)
+
+	for _, id := range []EnvironmentID{"", "E", "EN", "MCabc", "en123", "EN12/34"} {
+		_, err := links.Environment(id)
+		assert.Error(t, err, "%q should be rejected", id)
+	}
+
+	assert.Equal(t, "", EnvIDToURL("E"), "short IDs must not panic")
+	assert.Equal(t, "https://use.cloudshare.com/Ent/Environment.mvc/View/abc", EnvIDToURL("ENabc"))
+	assert.Equal(t, "", (&Environment{}).URL())
+}
+
+func TestLinksInvalidBaseURL(t *testing.T) {
+	_, err := (&Client{BaseURL: "not a url"}).Links()
+	assert.Error(t, err)
+}
@@ -0,0 +1,92 @@
+package cloudshare
+
+import (
+	"bytes"
+	"github.com/cloudshare/go-sdk/cloudshare/internal/redact"
+	"log/slog"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// DefaultRedactedFields returns the JSON field names (case insensitive) whose values are replaced
+// before request and response bodies are logged, unless Client.RedactedFields is set:
+// the VM passwords and console tokens returned by the API, and API keys
+func DefaultRedactedFields() []string {
+	return append([]string(nil), redact.DefaultFields...)
+}
+
+var authSecretPattern = regexp.MustCompile(`(?i)\b(token|hmac):[^;]*`)
+
+// redactAuthorization hides the token and hmac components of a cs_sha1 Authorization header
+func redactAuthorization(value string) string {
+	return authSecretPattern.ReplaceAllString(value, "${1}:"+redact.Redacted)
+}
+
+func redactHeaders(headers http.Header) http.Header {
+	ret := headers.Clone()
+	if auth := ret.Get("Authorization"); auth != "" {
+		ret.Set("Authorization", redactAuthorization(auth))
+	}
+	return ret
+}
+
+// redactBody replaces the values of fields in a JSON body.
+// Bodies that aren't valid JSON are returned as is.
+func redactBody(body []byte, fields []string) string {
+	return redact.JSON(body, fields)
+}
+
+func headerAttrs(headers http.Header) []any {
+	ret := make([]any, 0, len(headers))
+	for key, values := range headers {
+		ret = append(ret, slog.String(key, strings.Join(values, ", ")))
+	}
+	return ret
+}
+
+// loggingMiddleware logs every HTTP attempt to logger. Secrets in the
+// Authorization header and in JSON bodies are redacted. Failures are logged at warn level,
+// except 304 responses, which are successful cache revalidations even though send reports them as errors.
+func loggingMiddleware(logger *slog.Logger, logBodies bool, redactedFields []string) Middleware {
+	if redactedFields == nil {
+		redactedFields = redact.DefaultFields
+	}
+	return func(next Handler) Handler {
+		return func(call *Call) (*APIResponse, error) {
+			res, err := next(call)
+
+			attrs := []any{
+				slog.String("method", call.Method()),
+				slog.String("path", call.Path),
+				slog.String("query", call.Request.URL.RawQuery),
+				slog.Int("attempt", call.Attempt),
+				slog.Duration("latency", call.Elapsed()),
+				slog.Group("headers", headerAttrs(redactHeaders(call.Request.Header))...),
+			}
+			if res != nil {
+				attrs = append(attrs, slog.Int("status", res.StatusCode))
+			}
+			if logBodies {
+				if call.Request.GetBody != nil {
+					if body, e := call.Request.GetBody(); e == nil {
+						var buffer bytes.Buffer
+						buffer.ReadFrom(body)
+						attrs = append(attrs, slog.String("requestBody", redactBody(buffer.Bytes(), redactedFields)))
+					}
+				}
+				if res != nil {
+					attrs = append(attrs, slog.String("responseBody", redactBody(res.Body, redactedFields)))
+				}
+			}
+
+			level := slog.LevelDebug
+			if err != nil && (res == nil || res.StatusCode != http.StatusNotModified) {
+				level = slog.LevelWarn
+				attrs = append(attrs, slog.String("error", err.Error()))
+			}
+			logger.Log(call.Request.Context(), level, "cloudshare api call", attrs...)
+			return res, err
+		}
+	}
+}
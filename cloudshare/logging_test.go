@@ -0,0 +1,85 @@
+package cloudshare
+
+import (
+	"bytes"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"log/slog"
+	"net/http"
+	"testing"
+)
+
+func TestRedactAuthorization(t *testing.T) {
+	header := "cs_sha1 " + signToken("api_key", "api_id", "url", 1500000000, "abcdefghij")
+	redacted := redactAuthorization(header)
+	assert.Regexp(t, `^cs_sha1 userapiid:api_id;timestamp:\d+;token:REDACTED;hmac:REDACTED$`, redacted)
+}
+
+func TestRedactBody(t *testing.T) {
+	body := `{"vms": [{"name": "vm1", "password": "hunter2", "ConsoleToken": "abc", "cpuCount": 2}], "description": null}`
+	assert.JSONEq(t,
+		`{"vms": [{"name": "vm1", "password": "REDACTED", "ConsoleToken": "REDACTED", "cpuCount": 2}], "description": null}`,
+		redactBody([]byte(body), DefaultRedactedFields()))
+	assert.Equal(t, "not json", redactBody([]byte("not json"), nil))
+}
+
+func TestLogger(t *testing.T) {
+	c := newLocalClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"vms": [{"username": "admin", "password": "hunter2", "consoleToken": "secret-token"}]}`))
+	})
+	var buffer bytes.Buffer
+	c.Logger = slog.New(slog.NewJSONHandler(&buffer, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	c.LogBodies = true
+
+	env := EnvironmentExtended{}
+	require.Nil(t, c.GetEnvironmentExtended("ENabc", &env))
+	require.Equal(t, "hunter2", env.Vms[0].Password)
+
+	logged := buffer.String()
+	assert.Contains(t, logged, `"method":"GET"`)
+	assert.Contains(t, logged, `"path":"envs/actions/getextended"`)
+	assert.Contains(t, logged, `"query":"envId=ENabc"`)
+	assert.Contains(t, logged, `"status":200`)
+	assert.Contains(t, logged, `"latency"`)
+	assert.Contains(t, logged, `hmac:REDACTED`)
+	assert.Contains(t, logged, `admin`)
+	assert.NotContains(t, logged, "hunter2")
+	assert.NotContains(t, logged, "secret-token")
+}
+
+func TestLoggerRedactedFields(t *testing.T) {
+	c := newLocalClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"vms": [{"username": "admin", "password": "hunter2"}]}`))
+	})
+	var buffer bytes.Buffer
+	fields := []string{"username"}
+	c.APIID, c.APIKey = "api_id", "api_key"
+	c, err := c.With(WithLogger(slog.New(slog.NewJSONHandler(&buffer, &slog.HandlerOptions{Level: slog.LevelDebug})), true),
+		WithRedactedFields(fields...))
+	require.NoError(t, err)
+	fields[0] = "password"
+
+	require.Nil(t, c.GetEnvironmentExtended("ENabc", &EnvironmentExtended{}))
+	logged := buffer.String()
+	assert.NotContains(t, logged, "admin")
+	assert.Contains(t, logged, "hunter2", "the fields are copied, and replace the defaults")
+}
+
+func TestLoggerWithoutBodies(t *testing.T) {
+	c := newLocalClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		w.Write([]byte(`{"message": "bad request"}`))
+	})
+	var buffer bytes.Buffer
+	c.Logger = slog.New(slog.NewJSONHandler(&buffer, nil))
+
+	body := `{"password": "hunter2"}`
+	_, err := c.Request("POST", "policies", nil, &body)
+	require.NotNil(t, err)
+
+	logged := buffer.String()
+	assert.Contains(t, logged, `"level":"WARN"`)
+	assert.Contains(t, logged, `"status":400`)
+	assert.NotContains(t, logged, "responseBody")
+	assert.NotContains(t, logged, "hunter2")
+}
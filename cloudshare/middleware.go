@@ -0,0 +1,58 @@
+package cloudshare
+
+import (
+	"net/http"
+	"time"
+)
+
+// Call is a single HTTP attempt of an API call, as seen by middleware.
+type Call struct {
+	// Path is the API path relative to the API version, e.g. "envs/actions/getextended"
+	Path string
+	// Attempt is 1 for the first attempt, and increases on every retry
+	Attempt int
+	// Request is the signed HTTP request. Middleware may add headers to it,
+	// but must not change its URL, since the URL is part of the signature.
+	Request *http.Request
+	// Start is when the attempt started
+	Start time.Time
+}
+
+// Method returns the HTTP method of the call
+func (call *Call) Method() string {
+	return call.Request.Method
+}
+
+// Elapsed returns the time passed since the attempt started
+func (call *Call) Elapsed() time.Duration {
+	return time.Since(call.Start)
+}
+
+// Handler sends a Call and returns its result, exactly like Client.Request:
+// on HTTP failures both the response and an error are returned.
+type Handler func(call *Call) (*APIResponse, error)
+
+// Middleware wraps a Handler with cross-cutting behavior such as header injection,
+// logging or metrics. Middleware are applied in order, so the first one in
+// Client.Middleware is the outermost and sees the call first.
+//
+// Example - add a header to every request:
+//
+//		func(next cloudshare.Handler) cloudshare.Handler {
+//			return func(call *cloudshare.Call) (*cloudshare.APIResponse, error) {
+//				call.Request.Header.Set("X-Request-Id", newRequestID())
+//				return next(call)
+//			}
+//		}
+type Middleware func(next Handler) Handler
+
+func (c *Client) chain(handler Handler) Handler {
+	if c.Logger != nil {
+		// Innermost, so the log shows what was actually sent
+		handler = loggingMiddleware(c.Logger, c.LogBodies, c.RedactedFields)(handler)
+	}
+	for i := len(c.Middleware) - 1; i >= 0; i-- {
+		handler = c.Middleware[i](handler)
+	}
+	return handler
+}
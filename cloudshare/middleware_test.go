@@ -0,0 +1,69 @@
+package cloudshare
+
+import (
+	"errors"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+)
+
+func TestMiddlewareOrder(t *testing.T) {
+	var order []string
+	trace := func(name string) Middleware {
+		return func(next Handler) Handler {
+			return func(call *Call) (*APIResponse, error) {
+				order = append(order, name+" before")
+				res, err := next(call)
+				order = append(order, name+" after")
+				return res, err
+			}
+		}
+	}
+
+	c := newLocalClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[]`))
+	})
+	c.Middleware = []Middleware{trace("outer"), trace("inner")}
+
+	regions := []Region{}
+	require.Nil(t, c.GetRegions(&regions))
+	require.Equal(t, []string{"outer before", "inner before", "inner after", "outer after"}, order)
+}
+
+func TestMiddlewareSeesCall(t *testing.T) {
+	c := newLocalClient(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "injected", r.Header.Get("X-Test"))
+		w.WriteHeader(http.StatusNotFound)
+		w.Write([]byte(`{"code": "not_found", "message": "no such env"}`))
+	})
+
+	var seen *Call
+	var seenErr error
+	c.Middleware = []Middleware{func(next Handler) Handler {
+		return func(call *Call) (*APIResponse, error) {
+			call.Request.Header.Set("X-Test", "injected")
+			res, err := next(call)
+			seen, seenErr = call, err
+			require.Equal(t, http.StatusNotFound, res.StatusCode)
+			require.True(t, call.Elapsed() > 0)
+			return res, err
+		}
+	}}
+
+	require.NotNil(t, c.EnvironmentSuspend("ENabc"))
+	require.Equal(t, "envs/actions/suspend", seen.Path)
+	require.Equal(t, "PUT", seen.Method())
+	require.Equal(t, 1, seen.Attempt)
+	require.Equal(t, "ENabc", seen.Request.URL.Query().Get("envId"))
+	require.Equal(t, "no such env", seenErr.Error())
+}
+
+func TestMiddlewareShortCircuit(t *testing.T) {
+	c := &Client{Middleware: []Middleware{func(next Handler) Handler {
+		return func(call *Call) (*APIResponse, error) {
+			return nil, errors.New("blocked")
+		}
+	}}}
+	_, err := c.Request("GET", "regions", nil, nil)
+	require.EqualError(t, err, "blocked")
+}
@@ -0,0 +1,200 @@
+package cloudshare
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+)
+
+// ErrMissingCredentials is returned when no API key and ID are found
+var ErrMissingCredentials = errors.New("cloudshare: missing API credentials")
+
+// Option configures a Client created by NewClient or With
+type Option func(*Client)
+
+/*
+NewClient returns a client configured by opts, after validating that credentials are set
+and that the base URL is valid. The client is safe for concurrent use as long as its fields aren't changed
+once it's in use; NewClient doesn't prevent that, so derive a client with With instead.
+
+Without WithCredentials, WithCredentialsProvider or WithProfile, credentials are looked up once
+with DefaultCredentials (see Credentials). Empty credentials given explicitly are an error.
+
+Example:
+
+	client, err := cloudshare.NewClient(
+		cloudshare.WithCredentials(apiID, apiKey),
+		cloudshare.WithRetry(cloudshare.DefaultRetryPolicy()),
+	)
+*/
+func NewClient(opts ...Option) (*Client, error) {
+	return (&Client{}).With(opts...)
+}
+
+// With returns a shallow copy of the client with opts applied, validated as by NewClient.
+// The original client's fields are not modified. Only the Middleware and RedactedFields slices are copied: the derived client
+// shares its parent's Retry policy, RateLimiter, CircuitBreaker, Cache, HTTPClient, Credentials provider,
+// Signer and Logger by reference, unless opts replace them. Sharing the limiter, breaker and cache is
+// usually what's wanted, e.g. for clients of the same account with different base URLs.
+func (c *Client) With(opts ...Option) (*Client, error) {
+	ret := *c
+	ret.Middleware = append([]Middleware(nil), c.Middleware...)
+	if c.RedactedFields != nil {
+		ret.RedactedFields = append([]string(nil), c.RedactedFields...)
+	}
+	for _, opt := range opts {
+		opt(&ret)
+	}
+	err := ret.validate()
+	ret.credentialsSet = false
+	if err != nil {
+		return nil, err
+	}
+	return &ret, nil
+}
+
+func (c *Client) validate() error {
+	switch {
+	case c.APIKey != "" && c.APIID != "":
+	case c.APIKey != "" || c.APIID != "":
+		return fmt.Errorf("%w: both APIKey and APIID are required", ErrMissingCredentials)
+	case c.Credentials != nil:
+		if _, err := c.Credentials.Credentials(context.Background()); err != nil {
+			return err
+		}
+	case c.credentialsSet:
+		// explicitly empty credentials are a mistake, not a request for the ambient ones
+		return fmt.Errorf("%w: the credential option sets no API ID and key", ErrMissingCredentials)
+	default:
+		creds, err := DefaultCredentials().Credentials(context.Background())
+		if err != nil {
+			return err
+		}
+		c.APIID, c.APIKey = creds.APIID, creds.APIKey
+	}
+	_, err := c.baseURL()
+	return err
+}
+
+// WithCredentials sets the API ID and key, found on the user details page
+func WithCredentials(apiID string, apiKey string) Option {
+	return func(c *Client) {
+		c.APIID = apiID
+		c.APIKey = apiKey
+		c.credentialsSet = true
+	}
+}
+
+// WithCredentialsProvider sets Client.Credentials, which is called for every request,
+// and clears APIID and APIKey
+func WithCredentialsProvider(provider CredentialsProvider) Option {
+	return func(c *Client) {
+		c.APIID, c.APIKey = "", ""
+		c.Credentials = provider
+		c.credentialsSet = true
+	}
+}
+
+// WithProfile reads the credentials of the named profile from the credentials file, for every request
+func WithProfile(profile string) Option {
+	return WithCredentialsProvider(ProfileCredentials{Profile: profile})
+}
+
+// WithBaseURL sets Client.BaseURL
+func WithBaseURL(baseURL string) Option {
+	return func(c *Client) {
+		c.BaseURL = baseURL
+	}
+}
+
+// WithAPIHost sets Client.APIHost
+func WithAPIHost(host string) Option {
+	return func(c *Client) {
+		c.APIHost = host
+	}
+}
+
+// WithTags sets Client.Tags
+func WithTags(tags string) Option {
+	return func(c *Client) {
+		c.Tags = tags
+	}
+}
+
+// WithRetry sets a copy of policy as Client.Retry
+func WithRetry(policy *RetryPolicy) Option {
+	return func(c *Client) {
+		if policy == nil {
+			c.Retry = nil
+			return
+		}
+		copied := *policy
+		c.Retry = &copied
+	}
+}
+
+// WithRateLimiter sets Client.RateLimiter
+func WithRateLimiter(limiter *RateLimiter) Option {
+	return func(c *Client) {
+		c.RateLimiter = limiter
+	}
+}
+
+// WithCircuitBreaker sets Client.CircuitBreaker
+func WithCircuitBreaker(breaker *CircuitBreaker) Option {
+	return func(c *Client) {
+		c.CircuitBreaker = breaker
+	}
+}
+
+// WithCache sets Client.Cache
+func WithCache(cache *ResponseCache) Option {
+	return func(c *Client) {
+		c.Cache = cache
+	}
+}
+
+// WithHTTPClient sets Client.HTTPClient
+func WithHTTPClient(client *http.Client) Option {
+	return func(c *Client) {
+		c.HTTPClient = client
+	}
+}
+
+// WithMiddleware appends to Client.Middleware
+func WithMiddleware(middleware ...Middleware) Option {
+	return func(c *Client) {
+		c.Middleware = append(c.Middleware, middleware...)
+	}
+}
+
+// WithSigner sets Client.Signer
+func WithSigner(signer Signer) Option {
+	return func(c *Client) {
+		c.Signer = signer
+	}
+}
+
+// WithLogger sets Client.Logger. logBodies sets Client.LogBodies.
+func WithLogger(logger *slog.Logger, logBodies bool) Option {
+	return func(c *Client) {
+		c.Logger = logger
+		c.LogBodies = logBodies
+	}
+}
+
+// WithRedactedFields sets a copy of fields as Client.RedactedFields
+func WithRedactedFields(fields ...string) Option {
+	return func(c *Client) {
+		c.RedactedFields = append([]string{}, fields...)
+	}
+}
+
+// WithValidateTransitions sets Client.ValidateTransitions
+func WithValidateTransitions(validate bool) Option {
+	return func(c *Client) {
+		c.ValidateTransitions = validate
+	}
+}
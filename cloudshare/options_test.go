@@ -0,0 +1,80 @@
+package cloudshare
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"testing"
+)
+
+func TestNewClient(t *testing.T) {
+	isolateCredentials(t)
+	_, err := NewClient()
+	assert.True(t, errors.Is(err, ErrMissingCredentials))
+	_, err = NewClient(WithCredentials("id", ""))
+	assert.True(t, errors.Is(err, ErrMissingCredentials))
+	_, err = NewClient(WithCredentials("id", "key"), WithBaseURL("ftp://host/api/v3/"))
+	assert.Error(t, err)
+
+	retry := DefaultRetryPolicy()
+	c, err := NewClient(WithCredentials("id", "key"), WithAPIHost("staging.cloudshare.com"), WithRetry(retry), WithTags("tests"))
+	require.NoError(t, err)
+	assert.Equal(t, "id", c.APIID)
+	assert.Equal(t, "key", c.APIKey)
+	assert.Equal(t, "tests", c.tags())
+	assert.Equal(t, "https://staging.cloudshare.com/api/v3/regions", buildURLString(t, c, "regions", nil))
+	retry.MaxAttempts = 100
+	assert.Equal(t, 4, c.Retry.MaxAttempts, "the policy is copied")
+}
+
+func TestClientWith(t *testing.T) {
+	isolateCredentials(t)
+	noop := func(next Handler) Handler { return next }
+	limiter := NewRateLimiter(Limits{}, Limits{})
+	c, err := NewClient(WithCredentials("id", "key"), WithMiddleware(noop), WithRateLimiter(limiter))
+	require.NoError(t, err)
+
+	derived, err := c.With(WithBaseURL("http://localhost:8080/api/v3/"), WithMiddleware(noop))
+	require.NoError(t, err)
+	assert.Equal(t, "http://localhost:8080/api/v3/", derived.BaseURL)
+	assert.Len(t, derived.Middleware, 2)
+	assert.Equal(t, "", c.BaseURL, "the original client is unchanged")
+	assert.Len(t, c.Middleware, 1)
+	assert.Same(t, limiter, derived.RateLimiter, "pointer fields are shared")
+
+	_, err = c.With(WithCredentials("", ""))
+	assert.True(t, errors.Is(err, ErrMissingCredentials))
+}
+
+func TestExplicitEmptyCredentials(t *testing.T) {
+	isolateCredentials(t)
+	t.Setenv(EnvAPIID, "ambient-id")
+	t.Setenv(EnvAPIKey, "ambient-key")
+
+	c, err := NewClient()
+	require.NoError(t, err)
+	assert.Equal(t, "ambient-id", c.APIID, "no credential option falls back to the ambient credentials")
+
+	_, err = NewClient(WithCredentials("", ""))
+	assert.True(t, errors.Is(err, ErrMissingCredentials), "explicitly empty credentials don't fall back")
+	_, err = NewClient(WithCredentialsProvider(nil))
+	assert.True(t, errors.Is(err, ErrMissingCredentials))
+
+	derived, err := c.With(WithTags("tests"))
+	require.NoError(t, err)
+	assert.Equal(t, "ambient-id", derived.APIID)
+	assert.False(t, derived.credentialsSet)
+}
+
+func TestRequestDoesNotModifyClient(t *testing.T) {
+	c := newLocalClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("{}"))
+	})
+	c.Tags = ""
+	before := *c
+	_, err := c.Request("GET", "ping", nil, nil)
+	require.NoError(t, err)
+	assert.Equal(t, before, *c)
+	assert.Equal(t, "go_sdk", c.tags())
+}
@@ -0,0 +1,137 @@
+package cloudshare
+
+import (
+	"context"
+	"iter"
+	"reflect"
+)
+
+// DefaultPageSize is the number of items AllTemplates fetches per request
+const DefaultPageSize = 100
+
+// paginate yields the items of the pages returned by fetch, starting at skip.
+// It stops after limit items, unless limit is 0, after an empty, short or overlong page, and when a page
+// repeats the previous one. An overlong page means the server ignored take, and probably returned every item:
+// it's yielded in full, up to limit. A repeated page means it ignored skip, and fetching on would repeat the
+// same items forever.
+func paginate[T any](ctx context.Context, skip int, limit int, pageSize int, fetch func(ctx context.Context, skip int, take int) ([]T, error)) iter.Seq2[T, error] {
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	return func(yield func(T, error) bool) {
+		var zero T
+		var previous []T
+		for yielded := 0; limit == 0 || yielded < limit; {
+			if err := ctx.Err(); err != nil {
+				yield(zero, err)
+				return
+			}
+			take := pageSize
+			if limit != 0 && limit-yielded < take {
+				take = limit - yielded
+			}
+			page, err := fetch(ctx, skip+yielded, take)
+			if err != nil {
+				yield(zero, err)
+				return
+			}
+			if previous != nil && reflect.DeepEqual(page, previous) {
+				return
+			}
+			items := page
+			if limit != 0 {
+				items = items[:min(len(items), limit-yielded)]
+			}
+			for _, item := range items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			if len(page) != take {
+				return
+			}
+			yielded += take
+			previous = page
+		}
+	}
+}
+
+// all yields the items returned by a single call to fetch
+func all[T any](ctx context.Context, fetch func(ctx context.Context, ret *[]T) error) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		var items []T
+		if err := fetch(ctx, &items); err != nil {
+			var zero T
+			yield(zero, err)
+			return
+		}
+		for _, item := range items {
+			if !yield(item, nil) {
+				return
+			}
+		}
+	}
+}
+
+/*
+AllTemplates iterates over the templates matching params, fetching pageSize templates per request
+(DefaultPageSize if pageSize is 0). params.Skip and params.Take apply to the whole iteration.
+
+Like the other All... iterators, it stops at the first error, and breaking out of the loop stops fetching.
+Endpoints that don't support paging are fetched in a single request.
+
+Example:
+
+	for template, err := range client.AllTemplates(ctx, nil, 50) {
+		if err != nil {
+			return err
+		}
+		if template.Name == "Ubuntu 16.04 Server" {
+			break
+		}
+	}
+*/
+func (c *Client) AllTemplates(ctx context.Context, params *GetTemplateParams, pageSize int) iter.Seq2[VMTemplate, error] {
+	filter := GetTemplateParams{}
+	if params != nil {
+		filter = *params
+	}
+	return paginate(ctx, filter.Skip, filter.Take, pageSize, func(ctx context.Context, skip int, take int) ([]VMTemplate, error) {
+		page := filter
+		page.Skip, page.Take = skip, take
+		ret := []VMTemplate{}
+		err := c.GetTemplatesWithContext(ctx, &page, &ret)
+		return ret, err
+	})
+}
+
+// AllEnvironments iterates over the environments matching criteria (allowed | allvisible)
+func (c *Client) AllEnvironments(ctx context.Context, brief bool, criteria string) iter.Seq2[Environment, error] {
+	return all(ctx, func(ctx context.Context, ret *[]Environment) error {
+		return c.GetEnvironmentsWithContext(ctx, brief, criteria, (*Environments)(ret))
+	})
+}
+
+// AllProjects iterates over the user's projects
+func (c *Client) AllProjects(ctx context.Context) iter.Seq2[Project, error] {
+	return all(ctx, c.GetProjectsWithContext)
+}
+
+// AllBlueprints iterates over the blueprints of a project
+func (c *Client) AllBlueprints(ctx context.Context, projectID ProjectID) iter.Seq2[Blueprint, error] {
+	return all(ctx, func(ctx context.Context, ret *[]Blueprint) error {
+		return c.GetBlueprintsWithContext(ctx, projectID, ret)
+	})
+}
+
+// AllPolicies iterates over the policies of a project
+func (c *Client) AllPolicies(ctx context.Context, projectID ProjectID) iter.Seq2[Policy, error] {
+	return all(ctx, func(ctx context.Context, ret *[]Policy) error {
+		return c.GetPoliciesWithContext(ctx, projectID, ret)
+	})
+}
+
+// AllRegions iterates over the available regions
+func (c *Client) AllRegions(ctx context.Context) iter.Seq2[Region, error] {
+	return all(ctx, c.GetRegionsWithContext)
+}
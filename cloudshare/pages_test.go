@@ -0,0 +1,142 @@
+package cloudshare
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"strconv"
+	"sync/atomic"
+	"testing"
+)
+
+// newTemplatesClient serves count templates named "t0", "t1"... honoring skip and take
+func newTemplatesClient(t *testing.T, count int, requests *atomic.Int32) *Client {
+	return newLocalClient(t, func(w http.ResponseWriter, r *http.Request) {
+		requests.Add(1)
+		skip, _ := strconv.Atoi(r.URL.Query().Get("skip"))
+		take, _ := strconv.Atoi(r.URL.Query().Get("take"))
+		ret := []VMTemplate{}
+		for i := skip; i < count && (take == 0 || i < skip+take); i++ {
+			ret = append(ret, VMTemplate{Name: fmt.Sprintf("t%d", i)})
+		}
+		json.NewEncoder(w).Encode(ret)
+	})
+}
+
+func TestAllTemplatesPages(t *testing.T) {
+	var requests atomic.Int32
+	c := newTemplatesClient(t, 25, &requests)
+
+	names := []string{}
+	for template, err := range c.AllTemplates(context.Background(), nil, 10) {
+		require.NoError(t, err)
+		names = append(names, template.Name)
+	}
+	assert.Len(t, names, 25)
+	assert.Equal(t, "t24", names[24])
+	assert.Equal(t, int32(3), requests.Load())
+}
+
+func TestAllTemplatesSkipTake(t *testing.T) {
+	var requests atomic.Int32
+	c := newTemplatesClient(t, 25, &requests)
+
+	names := []string{}
+	for template, err := range c.AllTemplates(context.Background(), &GetTemplateParams{Skip: 5, Take: 12}, 10) {
+		require.NoError(t, err)
+		names = append(names, template.Name)
+	}
+	assert.Len(t, names, 12)
+	assert.Equal(t, "t5", names[0])
+	assert.Equal(t, "t16", names[11])
+	assert.Equal(t, int32(2), requests.Load())
+}
+
+func TestAllTemplatesEarlyTermination(t *testing.T) {
+	var requests atomic.Int32
+	c := newTemplatesClient(t, 1000, &requests)
+
+	count := 0
+	for _, err := range c.AllTemplates(context.Background(), nil, 0) {
+		require.NoError(t, err)
+		count++
+		if count == DefaultPageSize+1 {
+			break
+		}
+	}
+	assert.Equal(t, int32(2), requests.Load(), "no pages are fetched after break")
+}
+
+func TestAllTemplatesIgnoredPaging(t *testing.T) {
+	for count, expected := range map[int]int32{0: 1, 25: 1, 10: 2} {
+		var requests atomic.Int32
+		c := newLocalClient(t, func(w http.ResponseWriter, r *http.Request) {
+			requests.Add(1)
+			ret := []VMTemplate{}
+			for i := 0; i < count; i++ {
+				ret = append(ret, VMTemplate{Name: fmt.Sprintf("t%d", i)})
+			}
+			json.NewEncoder(w).Encode(ret)
+		})
+
+		names := []string{}
+		for template, err := range c.AllTemplates(context.Background(), nil, 10) {
+			require.NoError(t, err)
+			names = append(names, template.Name)
+		}
+		assert.Len(t, names, count, "overlong pages are yielded in full")
+		assert.Equal(t, expected, requests.Load(), "a server ignoring skip returns empty, overlong or repeated pages, which end the iteration")
+	}
+
+	c := newLocalClient(t, func(w http.ResponseWriter, r *http.Request) {
+		ret := []VMTemplate{}
+		for i := 0; i < 25; i++ {
+			ret = append(ret, VMTemplate{Name: fmt.Sprintf("t%d", i)})
+		}
+		json.NewEncoder(w).Encode(ret)
+	})
+	count := 0
+	for _, err := range c.AllTemplates(context.Background(), &GetTemplateParams{Take: 15}, 10) {
+		require.NoError(t, err)
+		count++
+	}
+	assert.Equal(t, 15, count, "overlong pages are still capped by params.Take")
+}
+
+func TestAllTemplatesCancelled(t *testing.T) {
+	var requests atomic.Int32
+	c := newTemplatesClient(t, 25, &requests)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	var err error
+	count := 0
+	for _, err = range c.AllTemplates(ctx, nil, 10) {
+		if err != nil {
+			break
+		}
+		count++
+		if count == 10 {
+			cancel()
+		}
+	}
+	assert.True(t, errors.Is(err, context.Canceled))
+	assert.Equal(t, 10, count)
+	assert.Equal(t, int32(1), requests.Load())
+}
+
+func TestAllProjectsError(t *testing.T) {
+	c := newLocalClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusForbidden)
+	})
+	count := 0
+	for _, err := range c.AllProjects(context.Background()) {
+		assert.True(t, errors.Is(err, ErrForbidden))
+		count++
+	}
+	assert.Equal(t, 1, count)
+}
@@ -0,0 +1,180 @@
+package cloudshare
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Limits caps the rate and concurrency of a class of requests. Zero values mean unlimited.
+type Limits struct {
+	// RequestsPerSecond is the sustained request rate
+	RequestsPerSecond float64
+	// Burst is the number of requests that may be sent at once after a quiet period. Defaults to 1.
+	Burst int
+	// MaxInFlight caps the number of concurrent requests
+	MaxInFlight int
+}
+
+/*
+RateLimiter throttles the requests of the clients it's set on, so that fanning out many calls
+doesn't trip the API's throttling. Reads (GET, HEAD and OPTIONS requests) and actions (all other methods)
+are limited separately. Every attempt of a request counts, including retries.
+
+Requests wait for their turn, until their context is done. When the API responds with 429 Too Many Requests,
+the limiter pauses the class of requests for the Retry-After delay (or a second), and halves its rate.
+The rate recovers gradually as requests succeed.
+
+Create a RateLimiter with NewRateLimiter. It's safe for concurrent use; share one between clients to share its limits.
+
+Example:
+
+	limiter := cloudshare.NewRateLimiter(
+		cloudshare.Limits{RequestsPerSecond: 20, Burst: 5, MaxInFlight: 10},
+		cloudshare.Limits{RequestsPerSecond: 2, MaxInFlight: 4},
+	)
+	client, err := cloudshare.NewClient(cloudshare.WithRateLimiter(limiter))
+*/
+type RateLimiter struct {
+	reads   *bucket
+	actions *bucket
+}
+
+// NewRateLimiter returns a limiter applying reads to read requests and actions to all other requests
+func NewRateLimiter(reads Limits, actions Limits) *RateLimiter {
+	return &RateLimiter{reads: newBucket(reads), actions: newBucket(actions)}
+}
+
+func (l *RateLimiter) bucket(method string) *bucket {
+	if isReadMethod(method) {
+		return l.reads
+	}
+	return l.actions
+}
+
+// wait blocks until a request with the given method may be sent, and returns a function to call once it's done.
+// A nil limiter doesn't block.
+func (l *RateLimiter) wait(ctx context.Context, method string) (func(res *APIResponse, err error), error) {
+	if l == nil {
+		return func(*APIResponse, error) {}, nil
+	}
+	return l.bucket(method).wait(ctx)
+}
+
+// minRateFraction is the lowest fraction of its configured rate that a bucket slows down to on 429 responses
+const minRateFraction = 1.0 / 16
+
+// defaultThrottlePause is how long a bucket pauses on a 429 response without a Retry-After header
+const defaultThrottlePause = time.Second
+
+// bucket is a token bucket with a semaphore capping requests in flight
+type bucket struct {
+	limits   Limits
+	inFlight chan struct{}
+
+	mu          sync.Mutex
+	rate        float64
+	tokens      float64
+	last        time.Time
+	pausedUntil time.Time
+}
+
+func newBucket(limits Limits) *bucket {
+	if limits.Burst <= 0 {
+		limits.Burst = 1
+	}
+	b := &bucket{
+		limits: limits,
+		rate:   limits.RequestsPerSecond,
+		tokens: float64(limits.Burst),
+	}
+	if limits.MaxInFlight > 0 {
+		b.inFlight = make(chan struct{}, limits.MaxInFlight)
+	}
+	return b
+}
+
+func (b *bucket) wait(ctx context.Context) (func(res *APIResponse, err error), error) {
+	if b.inFlight != nil {
+		select {
+		case b.inFlight <- struct{}{}:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	release := func() {
+		if b.inFlight != nil {
+			<-b.inFlight
+		}
+	}
+
+	delay, took := b.reserve(time.Now())
+	if !sleepContext(ctx, delay) {
+		if took {
+			b.mu.Lock()
+			b.tokens = min(b.tokens+1, float64(b.limits.Burst))
+			b.mu.Unlock()
+		}
+		release()
+		return nil, ctx.Err()
+	}
+	return func(res *APIResponse, err error) {
+		release()
+		b.observe(res, time.Now())
+	}, nil
+}
+
+// reserve takes a token, possibly going into debt, and returns how long to wait before using it
+func (b *bucket) reserve(now time.Time) (time.Duration, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var delay time.Duration
+	if now.Before(b.pausedUntil) {
+		delay = b.pausedUntil.Sub(now)
+	}
+	if b.rate <= 0 {
+		return delay, false
+	}
+	if !b.last.IsZero() {
+		b.tokens += now.Sub(b.last).Seconds() * b.rate
+		if b.tokens > float64(b.limits.Burst) {
+			b.tokens = float64(b.limits.Burst)
+		}
+	}
+	b.last = now
+	b.tokens--
+	if b.tokens < 0 {
+		if wait := time.Duration(-b.tokens / b.rate * float64(time.Second)); wait > delay {
+			delay = wait
+		}
+	}
+	return delay, true
+}
+
+// observe adapts the bucket to a response: 429 pauses it and halves its rate, success restores the rate
+func (b *bucket) observe(res *APIResponse, now time.Time) {
+	if res == nil {
+		return
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	configured := b.limits.RequestsPerSecond
+	switch {
+	case res.StatusCode == http.StatusTooManyRequests:
+		pause, ok := parseRetryAfter(res.Headers.Get("Retry-After"), now)
+		if !ok {
+			pause = defaultThrottlePause
+		}
+		if until := now.Add(pause); until.After(b.pausedUntil) {
+			b.pausedUntil = until
+		}
+		if configured > 0 {
+			b.rate = max(b.rate/2, configured*minRateFraction)
+		}
+	case res.StatusCode/100 == 2 && b.rate < configured:
+		b.rate = min(b.rate+configured/10, configured)
+	}
+}
@@ -0,0 +1,112 @@
+package cloudshare
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRateLimiterRate(t *testing.T) {
+	c := newLocalClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[]`))
+	})
+	c.RateLimiter = NewRateLimiter(Limits{RequestsPerSecond: 50, Burst: 2}, Limits{})
+
+	start := time.Now()
+	for i := 0; i < 7; i++ {
+		_, err := c.Request("GET", "projects", nil, nil)
+		require.NoError(t, err)
+	}
+	// 2 requests in the burst, then one every 20ms
+	assert.GreaterOrEqual(t, time.Since(start), 90*time.Millisecond)
+
+	start = time.Now()
+	for i := 0; i < 7; i++ {
+		_, err := c.Request("POST", "projects", nil, nil)
+		require.NoError(t, err)
+	}
+	assert.Less(t, time.Since(start), 90*time.Millisecond, "actions aren't limited by the reads' rate")
+}
+
+func TestRateLimiterMaxInFlight(t *testing.T) {
+	var inFlight, peak atomic.Int32
+	c := newLocalClient(t, func(w http.ResponseWriter, r *http.Request) {
+		n := inFlight.Add(1)
+		defer inFlight.Add(-1)
+		for {
+			p := peak.Load()
+			if n <= p || peak.CompareAndSwap(p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		w.Write([]byte(`[]`))
+	})
+	c.RateLimiter = NewRateLimiter(Limits{}, Limits{MaxInFlight: 3})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 12; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			_, err := c.Request("PUT", "envs/actions/suspend", nil, nil)
+			assert.NoError(t, err)
+		}()
+	}
+	wg.Wait()
+	assert.Equal(t, int32(3), peak.Load())
+}
+
+func TestRateLimiterContextCancelled(t *testing.T) {
+	c := newLocalClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[]`))
+	})
+	c.RateLimiter = NewRateLimiter(Limits{RequestsPerSecond: 0.1}, Limits{})
+	_, err := c.Request("GET", "projects", nil, nil)
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err = c.RequestWithContext(ctx, "GET", "projects", nil, nil)
+	assert.True(t, errors.Is(err, context.DeadlineExceeded))
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestRateLimiterAdaptsTo429(t *testing.T) {
+	b := newBucket(Limits{RequestsPerSecond: 10})
+	now := time.Now()
+	headers := http.Header{}
+	headers.Set("Retry-After", "2")
+	b.observe(&APIResponse{StatusCode: http.StatusTooManyRequests, Headers: headers}, now)
+	assert.Equal(t, 5.0, b.rate)
+	assert.Equal(t, now.Add(2*time.Second), b.pausedUntil)
+
+	delay, took := b.reserve(now)
+	assert.True(t, took)
+	assert.Equal(t, 2*time.Second, delay, "paused until Retry-After")
+
+	for i := 0; i < 10; i++ {
+		b.observe(&APIResponse{StatusCode: http.StatusTooManyRequests, Headers: http.Header{}}, now)
+	}
+	assert.Equal(t, 10*minRateFraction, b.rate)
+
+	for i := 0; i < 20; i++ {
+		b.observe(&APIResponse{StatusCode: http.StatusOK}, now)
+	}
+	assert.Equal(t, 10.0, b.rate, "the rate recovers up to the configured rate")
+}
+
+func TestRateLimiterUnlimited(t *testing.T) {
+	b := newBucket(Limits{})
+	for i := 0; i < 100; i++ {
+		delay, _ := b.reserve(time.Now())
+		assert.Zero(t, delay)
+	}
+}
@@ -0,0 +1,126 @@
+package cloudshare
+
+import (
+	"cmp"
+	"fmt"
+)
+
+// MBPerGB is the number of megabytes in a gigabyte, as the API counts them
+const MBPerGB = 1024
+
+// Resources is an amount of compute resources, e.g. the footprint of a VM or template,
+// or an environment's resource quota. Disk and memory sizes are in megabytes.
+//
+// Amounts of resources are only partially ordered: use FitsWithin to check whether one fits in another,
+// and == to check equality. Compare is a total order for sorting only, and says nothing about capacity.
+type Resources struct {
+	CPUCount     int `json:"cpuCount"`
+	DiskSizeMB   int `json:"diskSizeMB"`
+	MemorySizeMB int `json:"memorySizeMB"`
+}
+
+// SumResources returns the total of all resources
+func SumResources(all ...Resources) Resources {
+	total := Resources{}
+	for _, r := range all {
+		total = total.Add(r)
+	}
+	return total
+}
+
+// Add returns r + other
+func (r Resources) Add(other Resources) Resources {
+	return Resources{
+		CPUCount:     r.CPUCount + other.CPUCount,
+		DiskSizeMB:   r.DiskSizeMB + other.DiskSizeMB,
+		MemorySizeMB: r.MemorySizeMB + other.MemorySizeMB,
+	}
+}
+
+// Sub returns r - other. Fields may become negative, e.g. when subtracting usage from an exceeded quota.
+func (r Resources) Sub(other Resources) Resources {
+	return Resources{
+		CPUCount:     r.CPUCount - other.CPUCount,
+		DiskSizeMB:   r.DiskSizeMB - other.DiskSizeMB,
+		MemorySizeMB: r.MemorySizeMB - other.MemorySizeMB,
+	}
+}
+
+// Mul returns r times n, e.g. the footprint of n instances of a template
+func (r Resources) Mul(n int) Resources {
+	return Resources{
+		CPUCount:     r.CPUCount * n,
+		DiskSizeMB:   r.DiskSizeMB * n,
+		MemorySizeMB: r.MemorySizeMB * n,
+	}
+}
+
+// FitsWithin reports whether none of r's fields is larger than limit's
+func (r Resources) FitsWithin(limit Resources) bool {
+	return r.CPUCount <= limit.CPUCount && r.DiskSizeMB <= limit.DiskSizeMB && r.MemorySizeMB <= limit.MemorySizeMB
+}
+
+// Compare orders resources by CPU count, then memory size, then disk size, returning -1, 0 or +1
+// like cmp.Compare, e.g. to sort templates with slices.SortFunc. r.Compare(other) < 0 doesn't mean
+// that r fits within other; use FitsWithin for that.
+func (r Resources) Compare(other Resources) int {
+	if c := cmp.Compare(r.CPUCount, other.CPUCount); c != 0 {
+		return c
+	}
+	if c := cmp.Compare(r.MemorySizeMB, other.MemorySizeMB); c != 0 {
+		return c
+	}
+	return cmp.Compare(r.DiskSizeMB, other.DiskSizeMB)
+}
+
+// IsZero reports whether all fields are zero
+func (r Resources) IsZero() bool {
+	return r == Resources{}
+}
+
+// DiskSizeGB returns the disk size in gigabytes
+func (r Resources) DiskSizeGB() float64 {
+	return float64(r.DiskSizeMB) / MBPerGB
+}
+
+// MemorySizeGB returns the memory size in gigabytes
+func (r Resources) MemorySizeGB() float64 {
+	return float64(r.MemorySizeMB) / MBPerGB
+}
+
+func (r Resources) String() string {
+	return fmt.Sprintf("%d CPUs, %gGB RAM, %gGB disk", r.CPUCount, r.MemorySizeGB(), r.DiskSizeGB())
+}
+
+// Resources returns the VM's footprint. The API reports a VM's disk size in gigabytes.
+func (vm *VMAccessDetails) Resources() Resources {
+	return Resources{
+		CPUCount:     vm.CPUCount,
+		DiskSizeMB:   vm.DiskSizeGB * MBPerGB,
+		MemorySizeMB: vm.MemorySizeMB,
+	}
+}
+
+// Resources returns the total footprint of the environment's VMs
+func (e *EnvironmentExtended) Resources() Resources {
+	total := Resources{}
+	for i := range e.Vms {
+		total = total.Add(e.Vms[i].Resources())
+	}
+	return total
+}
+
+// ResourceQuota is a project or subscription quota. Nil fields mean no limit.
+type ResourceQuota struct {
+	CPUCount     *int `json:"cpuCount"`
+	DiskSizeMB   *int `json:"diskSizeMB"`
+	MemorySizeMB *int `json:"memorySizeMB"`
+}
+
+// Allows reports whether r fits within the quota
+func (q ResourceQuota) Allows(r Resources) bool {
+	within := func(value int, limit *int) bool {
+		return limit == nil || value <= *limit
+	}
+	return within(r.CPUCount, q.CPUCount) && within(r.DiskSizeMB, q.DiskSizeMB) && within(r.MemorySizeMB, q.MemorySizeMB)
+}
@@ -0,0 +1,52 @@
+package cloudshare
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+)
+
+func TestResourcesArithmetic(t *testing.T) {
+	small := Resources{CPUCount: 1, DiskSizeMB: 10240, MemorySizeMB: 1024}
+	large := Resources{CPUCount: 4, DiskSizeMB: 40960, MemorySizeMB: 8192}
+
+	assert.Equal(t, Resources{CPUCount: 5, DiskSizeMB: 51200, MemorySizeMB: 9216}, small.Add(large))
+	assert.Equal(t, Resources{CPUCount: 3, DiskSizeMB: 30720, MemorySizeMB: 7168}, large.Sub(small))
+	assert.Equal(t, Resources{CPUCount: 3, DiskSizeMB: 30720, MemorySizeMB: 3072}, small.Mul(3))
+	assert.Equal(t, small.Add(large).Add(small), SumResources(small, large, small))
+	assert.True(t, SumResources().IsZero())
+
+	assert.True(t, small.FitsWithin(large))
+	assert.True(t, small.FitsWithin(small))
+	assert.False(t, large.FitsWithin(small))
+	assert.False(t, Resources{CPUCount: 1, MemorySizeMB: 16384}.FitsWithin(large), "any field over the limit")
+
+	assert.Equal(t, -1, small.Compare(large))
+	assert.Equal(t, 1, large.Compare(small))
+	assert.Equal(t, 0, small.Compare(small))
+	moreDisk := Resources{CPUCount: 1, DiskSizeMB: 20480, MemorySizeMB: 512}
+	assert.Equal(t, 1, small.Compare(moreDisk), "memory is compared before disk")
+	assert.False(t, moreDisk.FitsWithin(small), "lower in the order doesn't mean it fits")
+
+	assert.Equal(t, 10.0, small.DiskSizeGB())
+	assert.Equal(t, 1.0, small.MemorySizeGB())
+	assert.Equal(t, "1 CPUs, 1GB RAM, 10GB disk", small.String())
+}
+
+func TestEnvironmentFootprint(t *testing.T) {
+	env := EnvironmentExtended{}
+	loadFixture(t, "envs_getextended.json", &env)
+	footprint := env.Resources()
+	assert.Equal(t, Resources{CPUCount: 6, DiskSizeMB: 120 * MBPerGB, MemorySizeMB: 12288}, footprint)
+
+	details := ProjectDetails{}
+	loadFixture(t, "project_details.json", &details)
+	assert.True(t, footprint.FitsWithin(details.EnvironmentResourceQuota))
+	assert.False(t, footprint.Mul(3).FitsWithin(details.EnvironmentResourceQuota))
+
+	require.NotNil(t, details.ProjectResourceQuota.CPUCount)
+	assert.True(t, details.ProjectResourceQuota.Allows(footprint))
+	assert.False(t, details.ProjectResourceQuota.Allows(Resources{CPUCount: 65}))
+	assert.True(t, details.ProjectResourceQuota.Allows(Resources{MemorySizeMB: 1 << 30}), "memory isn't limited")
+	assert.True(t, details.SubscriptionResourceQuota.Allows(footprint.Mul(100)))
+}
@@ -0,0 +1,174 @@
+package cloudshare
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RetryPolicy controls how Client retries requests that failed with a
+// network error or a transient HTTP status (429, 5xx by default).
+//
+// Every attempt is re-signed with a fresh auth token. Only idempotent
+// methods (GET, HEAD, OPTIONS, PUT, DELETE) are retried unless RetryPOST is set.
+//
+// Zero values fall back to the values of DefaultRetryPolicy.
+type RetryPolicy struct {
+	// MaxAttempts is the total number of attempts, including the first one.
+	MaxAttempts int
+	// BaseDelay is the delay before the first retry. It doubles on every subsequent retry.
+	BaseDelay time.Duration
+	// MaxDelay caps the exponential backoff.
+	MaxDelay time.Duration
+	// Jitter is the fraction (0 to 1) of each delay that is randomized.
+	Jitter float64
+	// RetryableStatus decides whether a response status code is worth retrying.
+	RetryableStatus func(statusCode int) bool
+	// RetryPOST enables retrying POST requests, which are not idempotent.
+	RetryPOST bool
+	// IgnoreRetryAfter disables honoring the Retry-After response header.
+	IgnoreRetryAfter bool
+}
+
+// DefaultRetryPolicy returns a policy of 4 attempts with exponential backoff
+// from 500ms up to 30s and 50% jitter.
+func DefaultRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts:     4,
+		BaseDelay:       500 * time.Millisecond,
+		MaxDelay:        30 * time.Second,
+		Jitter:          0.5,
+		RetryableStatus: DefaultRetryableStatus,
+	}
+}
+
+// DefaultRetryableStatus returns true for 429 Too Many Requests and for 5xx
+// server errors other than 501 Not Implemented.
+func DefaultRetryableStatus(statusCode int) bool {
+	if statusCode == http.StatusTooManyRequests {
+		return true
+	}
+	return statusCode/100 == 5 && statusCode != http.StatusNotImplemented
+}
+
+func isIdempotent(method string) bool {
+	switch method {
+	case "GET", "HEAD", "OPTIONS", "PUT", "DELETE":
+		return true
+	}
+	return false
+}
+
+// attempts returns how many times a request with the given method may be sent
+func (p *RetryPolicy) attempts(method string) int {
+	if p == nil {
+		return 1
+	}
+	method = strings.ToUpper(method)
+	if !isIdempotent(method) && !(method == "POST" && p.RetryPOST) {
+		return 1
+	}
+	if p.MaxAttempts <= 0 {
+		return DefaultRetryPolicy().MaxAttempts
+	}
+	return p.MaxAttempts
+}
+
+func (p *RetryPolicy) shouldRetry(ctx context.Context, res *APIResponse, err error) bool {
+	if err == nil || ctx.Err() != nil {
+		return false
+	}
+	if res == nil {
+		// Only transport failures are transient: local failures, e.g. to sign the request, aren't.
+		var apiErr *APIError
+		if errors.As(err, &apiErr) {
+			return apiErr.Retryable
+		}
+		var netErr net.Error
+		return errors.As(err, &netErr)
+	}
+	return p.retryableStatus(res.StatusCode)
+}
+
+// retryableStatus decides whether a response status is worth retrying with the policy.
+// A nil policy uses DefaultRetryableStatus.
+func (p *RetryPolicy) retryableStatus(statusCode int) bool {
+	if p == nil || p.RetryableStatus == nil {
+		return DefaultRetryableStatus(statusCode)
+	}
+	return p.RetryableStatus(statusCode)
+}
+
+// delay returns how long to wait after the given (1-based) failed attempt
+func (p *RetryPolicy) delay(attempt int, res *APIResponse) time.Duration {
+	base, max := p.BaseDelay, p.MaxDelay
+	if base <= 0 {
+		base = DefaultRetryPolicy().BaseDelay
+	}
+	if max <= 0 {
+		max = DefaultRetryPolicy().MaxDelay
+	}
+
+	d := base
+	for i := 1; i < attempt && d < max; i++ {
+		d *= 2
+	}
+	if d > max {
+		d = max
+	}
+	if p.Jitter > 0 {
+		jitter := p.Jitter
+		if jitter > 1 {
+			jitter = 1
+		}
+		d -= time.Duration(jitter * rand.Float64() * float64(d))
+	}
+
+	if !p.IgnoreRetryAfter && res != nil {
+		if after, ok := parseRetryAfter(res.Headers.Get("Retry-After"), time.Now()); ok && after > d {
+			d = after
+		}
+	}
+	return d
+}
+
+// parseRetryAfter parses a Retry-After header in either its delay-seconds or HTTP-date form
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil {
+		if seconds < 0 {
+			return 0, false
+		}
+		return time.Duration(seconds) * time.Second, true
+	}
+	if t, err := http.ParseTime(value); err == nil {
+		d := t.Sub(now)
+		if d < 0 {
+			d = 0
+		}
+		return d, true
+	}
+	return 0, false
+}
+
+// sleepContext waits for d, returning false if ctx is done first
+func sleepContext(ctx context.Context, d time.Duration) bool {
+	if d <= 0 {
+		return ctx.Err() == nil
+	}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
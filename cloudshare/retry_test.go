@@ -0,0 +1,179 @@
+package cloudshare
+
+import (
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"sync"
+	"testing"
+	"time"
+)
+
+func fastRetryPolicy() *RetryPolicy {
+	return &RetryPolicy{
+		MaxAttempts: 3,
+		BaseDelay:   time.Millisecond,
+		MaxDelay:    5 * time.Millisecond,
+	}
+}
+
+func TestRetryTransientFailures(t *testing.T) {
+	var mu sync.Mutex
+	var tokens []string
+	c := newLocalClient(t, func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		tokens = append(tokens, r.Header.Get("Authorization"))
+		if len(tokens) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`[]`))
+	})
+	c.Retry = fastRetryPolicy()
+
+	regions := []Region{}
+	require.Nil(t, c.GetRegions(&regions))
+	require.Len(t, tokens, 3)
+	assert.NotEqual(t, tokens[0], tokens[1], "each attempt should be re-signed")
+	assert.NotEqual(t, tokens[1], tokens[2], "each attempt should be re-signed")
+}
+
+func TestRetryGivesUp(t *testing.T) {
+	attempts := 0
+	c := newLocalClient(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusBadGateway)
+	})
+	c.Retry = fastRetryPolicy()
+
+	res, err := c.Request("GET", "regions", nil, nil)
+	require.NotNil(t, err)
+	require.Equal(t, http.StatusBadGateway, res.StatusCode)
+	require.Equal(t, 3, attempts)
+}
+
+func TestRetrySkipsNonRetryable(t *testing.T) {
+	attempts := 0
+	c := newLocalClient(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusNotFound)
+	})
+	c.Retry = fastRetryPolicy()
+
+	_, err := c.Request("GET", "regions", nil, nil)
+	require.NotNil(t, err)
+	require.Equal(t, 1, attempts)
+}
+
+func TestRetryPOSTIsOptIn(t *testing.T) {
+	attempts := 0
+	c := newLocalClient(t, func(w http.ResponseWriter, r *http.Request) {
+		attempts++
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	c.Retry = fastRetryPolicy()
+
+	body := "{}"
+	_, err := c.Request("POST", "policies", nil, &body)
+	require.NotNil(t, err)
+	require.Equal(t, 1, attempts)
+
+	attempts = 0
+	c.Retry.RetryPOST = true
+	_, err = c.Request("POST", "policies", nil, &body)
+	require.NotNil(t, err)
+	require.Equal(t, 3, attempts)
+}
+
+func TestRetrySkipsLocalFailures(t *testing.T) {
+	c := newLocalClient(t, func(w http.ResponseWriter, r *http.Request) {})
+	c.Retry = fastRetryPolicy()
+
+	signed := 0
+	c.Signer = SignerFunc(func(request *http.Request, creds Credentials) error {
+		signed++
+		return errors.New("no key")
+	})
+	_, err := c.Request("GET", "regions", nil, nil)
+	require.Error(t, err)
+	assert.Equal(t, 1, signed, "signing failures aren't retried")
+
+	c.Signer = nil
+	sent := 0
+	c.Middleware = []Middleware{func(next Handler) Handler {
+		return func(call *Call) (*APIResponse, error) {
+			sent++
+			return nil, errors.New("blocked")
+		}
+	}}
+	_, err = c.Request("GET", "regions", nil, nil)
+	require.Error(t, err)
+	assert.Equal(t, 1, sent, "errors other than transport failures aren't retried")
+}
+
+func TestRetryTransportFailures(t *testing.T) {
+	c := newLocalClient(t, func(w http.ResponseWriter, r *http.Request) {})
+	c.Retry = fastRetryPolicy()
+	sent := 0
+	c.Middleware = []Middleware{func(next Handler) Handler {
+		return func(call *Call) (*APIResponse, error) {
+			sent++
+			return nil, &APIError{InnerError: errors.New("connection reset"), Retryable: true}
+		}
+	}}
+	_, err := c.Request("GET", "regions", nil, nil)
+	require.Error(t, err)
+	assert.Equal(t, 3, sent)
+}
+
+func TestRetryableFollowsPolicy(t *testing.T) {
+	c := newLocalClient(t, func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	})
+	c.Retry = fastRetryPolicy()
+	c.Retry.RetryableStatus = func(statusCode int) bool { return statusCode == http.StatusConflict }
+
+	_, err := c.Request("GET", "regions", nil, nil)
+	var apiErr *APIError
+	require.True(t, errors.As(err, &apiErr))
+	assert.False(t, apiErr.Retryable, "the policy doesn't retry 503")
+
+	c.Retry = nil
+	_, err = c.Request("GET", "regions", nil, nil)
+	require.True(t, errors.As(err, &apiErr))
+	assert.True(t, apiErr.Retryable, "without a policy, DefaultRetryableStatus decides")
+}
+
+func TestRetryDelay(t *testing.T) {
+	p := &RetryPolicy{BaseDelay: time.Second, MaxDelay: 5 * time.Second}
+	assert.Equal(t, time.Second, p.delay(1, nil))
+	assert.Equal(t, 2*time.Second, p.delay(2, nil))
+	assert.Equal(t, 4*time.Second, p.delay(3, nil))
+	assert.Equal(t, 5*time.Second, p.delay(4, nil))
+
+	res := &APIResponse{Headers: http.Header{}}
+	res.Headers.Set("Retry-After", "10")
+	assert.Equal(t, 10*time.Second, p.delay(1, res))
+
+	p.Jitter = 0.5
+	for i := 0; i < 100; i++ {
+		d := p.delay(2, nil)
+		assert.True(t, d > time.Second && d <= 2*time.Second, "jittered delay %s out of range", d)
+	}
+}
+
+func TestParseRetryAfter(t *testing.T) {
+	now := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	d, ok := parseRetryAfter("120", now)
+	assert.True(t, ok)
+	assert.Equal(t, 2*time.Minute, d)
+
+	d, ok = parseRetryAfter(now.Add(30*time.Second).Format(http.TimeFormat), now)
+	assert.True(t, ok)
+	assert.Equal(t, 30*time.Second, d)
+
+	_, ok = parseRetryAfter("soon", now)
+	assert.False(t, ok)
+}
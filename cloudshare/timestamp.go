@@ -0,0 +1,97 @@
+package cloudshare
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// Timestamp is a point in time returned by the API, e.g. "2017-05-31T21:49:44" or "2017-03-14T09:26:53.589Z".
+// The API doesn't document the zone of times without one; they're assumed to be in UTC.
+//
+// A timestamp decoded from JSON keeps the value received, and re-encodes exactly as received as long as its Time
+// isn't changed. A value that can't be parsed doesn't fail decoding: Time is left zero, and Err returns why.
+// Empty and null timestamps decode to the zero time, and the zero time encodes as null.
+type Timestamp struct {
+	time.Time
+	raw []byte
+	err error
+}
+
+// timestampLayouts are tried in order. Fractional seconds are accepted by all of them.
+var timestampLayouts = []string{
+	"2006-01-02T15:04:05",
+	time.RFC3339,
+	"2006-01-02T15:04:05Z0700",
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04:05Z07:00",
+	"2006-01-02 15:04:05Z0700",
+}
+
+// ParseTimestamp parses a timestamp in one of the API's formats
+func ParseTimestamp(s string) (Timestamp, error) {
+	for _, layout := range timestampLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.UTC); err == nil {
+			return Timestamp{Time: t}, nil
+		}
+	}
+	return Timestamp{}, fmt.Errorf("invalid timestamp %q", s)
+}
+
+// Err returns the error parsing the value the timestamp was decoded from, if any
+func (t Timestamp) Err() error {
+	return t.err
+}
+
+// Raw returns the JSON value the timestamp was decoded from, or nil if it wasn't decoded
+func (t Timestamp) Raw() json.RawMessage {
+	return t.raw
+}
+
+func (t *Timestamp) UnmarshalJSON(data []byte) error {
+	parsed := Timestamp{raw: bytes.Clone(data)}
+	var s *string
+	if err := json.Unmarshal(data, &s); err != nil {
+		parsed.err = fmt.Errorf("invalid timestamp %s", data)
+	} else if s != nil && *s != "" {
+		ts, err := ParseTimestamp(*s)
+		parsed.Time, parsed.err = ts.Time, err
+	}
+	*t = parsed
+	return nil
+}
+
+func (t Timestamp) MarshalJSON() ([]byte, error) {
+	if t.raw != nil {
+		if t.err != nil && t.IsZero() {
+			return t.raw, nil
+		}
+		if received, err := t.received(); err == nil && received.Equal(t.Time) {
+			return t.raw, nil
+		}
+	}
+	if t.IsZero() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(t.UTC().Format(time.RFC3339Nano))
+}
+
+// received returns the time the timestamp was decoded from
+func (t Timestamp) received() (time.Time, error) {
+	var s *string
+	if err := json.Unmarshal(t.raw, &s); err != nil || s == nil || *s == "" {
+		return time.Time{}, err
+	}
+	parsed, err := ParseTimestamp(*s)
+	return parsed.Time, err
+}
+
+// TimeUntilExpiration returns how long until the environment expires, which is negative once it has expired,
+// or 0 if the expiration time is unknown
+func (e *EnvironmentExtended) TimeUntilExpiration() time.Duration {
+	if e.ExpirationTime.IsZero() {
+		return 0
+	}
+	return time.Until(e.ExpirationTime.Time)
+}
@@ -0,0 +1,87 @@
+package cloudshare
+
+import (
+	"encoding/json"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"testing"
+	"time"
+)
+
+func TestParseTimestamp(t *testing.T) {
+	cases := map[string]time.Time{
+		"2017-05-31T21:49:44":          time.Date(2017, 5, 31, 21, 49, 44, 0, time.UTC),
+		"2017-03-14T09:26:53.589":      time.Date(2017, 3, 14, 9, 26, 53, 589000000, time.UTC),
+		"2017-05-30T13:49:44Z":         time.Date(2017, 5, 30, 13, 49, 44, 0, time.UTC),
+		"2017-05-30T15:49:44.5+02:00":  time.Date(2017, 5, 30, 13, 49, 44, 500000000, time.UTC),
+		"2017-05-30T13:49:44.1234567Z": time.Date(2017, 5, 30, 13, 49, 44, 123456700, time.UTC),
+		"2017-05-31 21:49:44":          time.Date(2017, 5, 31, 21, 49, 44, 0, time.UTC),
+		"2017-05-31T21:49:44+0300":     time.Date(2017, 5, 31, 18, 49, 44, 0, time.UTC),
+		"2017-05-31 21:49:44.25-0700":  time.Date(2017, 6, 1, 4, 49, 44, 250000000, time.UTC),
+	}
+	for s, expected := range cases {
+		ts, err := ParseTimestamp(s)
+		require.NoError(t, err, s)
+		assert.True(t, expected.Equal(ts.Time), "%s parsed as %s", s, ts.Time)
+	}
+	_, err := ParseTimestamp("31/05/2017")
+	assert.Error(t, err)
+}
+
+func TestTimestampJSON(t *testing.T) {
+	var parsed struct {
+		A Timestamp `json:"a"`
+		B Timestamp `json:"b"`
+		C Timestamp `json:"c"`
+		D Timestamp `json:"d"`
+	}
+	input := `{"a":"2017-03-14T09:26:53.580","b":null,"c":"","d":"2017-05-30T13:49:44Z"}`
+	require.NoError(t, json.Unmarshal([]byte(input), &parsed))
+	assert.True(t, parsed.B.IsZero())
+	assert.True(t, parsed.C.IsZero())
+
+	buffer, err := json.Marshal(parsed)
+	require.NoError(t, err)
+	assert.Equal(t, input, string(buffer), "re-encoded as received")
+
+	parsed.A.Time = parsed.A.Add(time.Hour)
+	buffer, err = json.Marshal(parsed.A)
+	require.NoError(t, err)
+	assert.Equal(t, `"2017-03-14T10:26:53.58Z"`, string(buffer), "changed times are formatted")
+
+	buffer, err = json.Marshal(Timestamp{})
+	require.NoError(t, err)
+	assert.Equal(t, `null`, string(buffer))
+}
+
+func TestTimestampInvalidJSON(t *testing.T) {
+	var parsed struct {
+		A Timestamp `json:"a"`
+		B Timestamp `json:"b"`
+	}
+	input := `{"a":"yesterday","b":42}`
+	require.NoError(t, json.Unmarshal([]byte(input), &parsed), "invalid timestamps don't fail decoding")
+	assert.True(t, parsed.A.IsZero())
+	assert.Error(t, parsed.A.Err())
+	assert.Equal(t, `"yesterday"`, string(parsed.A.Raw()))
+	assert.Error(t, parsed.B.Err())
+
+	buffer, err := json.Marshal(parsed)
+	require.NoError(t, err)
+	assert.Equal(t, input, string(buffer), "re-encoded as received")
+
+	require.NoError(t, json.Unmarshal([]byte(`"2017-05-31T21:49:44"`), &parsed.A))
+	assert.NoError(t, parsed.A.Err())
+}
+
+func TestTimeUntilExpiration(t *testing.T) {
+	env := EnvironmentExtended{}
+	assert.Equal(t, time.Duration(0), env.TimeUntilExpiration())
+
+	env.ExpirationTime = Timestamp{Time: time.Now().Add(time.Hour)}
+	assert.InDelta(t, time.Hour, env.TimeUntilExpiration(), float64(time.Minute))
+
+	loadFixture(t, "envs_getextended.json", &env)
+	assert.Equal(t, time.Date(2017, 5, 31, 21, 49, 44, 0, time.UTC), env.ExpirationTime.Time)
+	assert.Less(t, env.TimeUntilExpiration(), time.Duration(0), "expired")
+}
@@ -0,0 +1,98 @@
+package cloudshare
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// defaultHTTPClient is shared by all clients that don't set Client.HTTPClient,
+// so connections to the API are pooled and kept alive across calls.
+var defaultHTTPClient = &http.Client{
+	Transport: newTransport(HTTPClientOptions{}),
+}
+
+// HTTPClientOptions configures the http.Client returned by NewHTTPClient.
+// All fields are optional.
+type HTTPClientOptions struct {
+	// ProxyURL is the proxy to send requests through, e.g. "http://proxy.local:3128".
+	// When empty, the HTTP_PROXY/HTTPS_PROXY environment variables are used.
+	ProxyURL string
+	// RootCAs is the set of root certificates used to verify the server.
+	// When nil, the host's root CA set is used.
+	RootCAs *x509.CertPool
+	// Certificates are client certificates presented to the server (mTLS).
+	Certificates []tls.Certificate
+	// InsecureSkipVerify disables server certificate verification. Only use it for testing.
+	InsecureSkipVerify bool
+	// Timeout limits the total time of a single HTTP request, including reading the body.
+	// Zero means no limit; prefer context deadlines for per-call limits.
+	Timeout time.Duration
+	// DialTimeout limits the time to establish a TCP connection. Defaults to 30 seconds.
+	DialTimeout time.Duration
+	// TLSHandshakeTimeout limits the TLS handshake. Defaults to 10 seconds.
+	TLSHandshakeTimeout time.Duration
+	// ResponseHeaderTimeout limits the time to wait for response headers after
+	// the request was written. Zero means no limit.
+	ResponseHeaderTimeout time.Duration
+}
+
+// NewHTTPClient returns an http.Client suitable for Client.HTTPClient
+func NewHTTPClient(opts HTTPClientOptions) (*http.Client, error) {
+	transport := newTransport(opts)
+	if opts.ProxyURL != "" {
+		proxy, err := url.Parse(opts.ProxyURL)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy URL %q: %s", opts.ProxyURL, err)
+		}
+		if proxy.Scheme == "" || proxy.Host == "" {
+			return nil, fmt.Errorf("invalid proxy URL %q: scheme and host are required", opts.ProxyURL)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+	return &http.Client{
+		Transport: transport,
+		Timeout:   opts.Timeout,
+	}, nil
+}
+
+func newTransport(opts HTTPClientOptions) *http.Transport {
+	dialTimeout := opts.DialTimeout
+	if dialTimeout == 0 {
+		dialTimeout = 30 * time.Second
+	}
+	handshakeTimeout := opts.TLSHandshakeTimeout
+	if handshakeTimeout == 0 {
+		handshakeTimeout = 10 * time.Second
+	}
+	return &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   dialTimeout,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          100,
+		MaxIdleConnsPerHost:   16,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   handshakeTimeout,
+		ResponseHeaderTimeout: opts.ResponseHeaderTimeout,
+		ExpectContinueTimeout: time.Second,
+		TLSClientConfig: &tls.Config{
+			RootCAs:            opts.RootCAs,
+			Certificates:       opts.Certificates,
+			InsecureSkipVerify: opts.InsecureSkipVerify,
+		},
+	}
+}
+
+func (c *Client) httpClient() *http.Client {
+	if c.HTTPClient != nil {
+		return c.HTTPClient
+	}
+	return defaultHTTPClient
+}
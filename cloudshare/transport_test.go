@@ -0,0 +1,85 @@
+package cloudshare
+
+import (
+	"crypto/x509"
+	"github.com/stretchr/testify/require"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync/atomic"
+	"testing"
+)
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
+
+func TestCustomRoundTripper(t *testing.T) {
+	called := false
+	c := &Client{
+		APIKey: "key",
+		APIID:  "id",
+		HTTPClient: &http.Client{Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			called = true
+			require.Contains(t, r.Header.Get("Authorization"), "cs_sha1 userapiid:id;")
+			return httptest.NewRecorder().Result(), nil
+		})},
+	}
+	_, err := c.Request("GET", "ping", nil, nil)
+	require.Nil(t, err)
+	require.True(t, called)
+}
+
+func TestConnectionReuse(t *testing.T) {
+	var conns int32
+	server := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`[]`))
+	}))
+	server.Config.ConnState = func(_ net.Conn, state http.ConnState) {
+		if state == http.StateNew {
+			atomic.AddInt32(&conns, 1)
+		}
+	}
+	server.StartTLS()
+	defer server.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(server.Certificate())
+	httpClient, err := NewHTTPClient(HTTPClientOptions{RootCAs: pool})
+	require.NoError(t, err)
+
+	u, _ := url.Parse(server.URL)
+	c := &Client{APIHost: u.Host, HTTPClient: httpClient}
+	for i := 0; i < 5; i++ {
+		regions := []Region{}
+		require.Nil(t, c.GetRegions(&regions))
+	}
+	require.Equal(t, int32(1), atomic.LoadInt32(&conns))
+}
+
+func TestUntrustedCertificateRejected(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+
+	httpClient, err := NewHTTPClient(HTTPClientOptions{})
+	require.NoError(t, err)
+	u, _ := url.Parse(server.URL)
+	c := &Client{APIHost: u.Host, HTTPClient: httpClient}
+	_, err = c.Request("GET", "ping", nil, nil)
+	require.NotNil(t, err)
+}
+
+func TestNewHTTPClientProxy(t *testing.T) {
+	_, err := NewHTTPClient(HTTPClientOptions{ProxyURL: "not a url"})
+	require.Error(t, err)
+
+	httpClient, err := NewHTTPClient(HTTPClientOptions{ProxyURL: "http://proxy.local:3128"})
+	require.NoError(t, err)
+	req, _ := http.NewRequest("GET", "https://use.cloudshare.com/api/v3/ping", nil)
+	proxy, err := httpClient.Transport.(*http.Transport).Proxy(req)
+	require.NoError(t, err)
+	require.Equal(t, "proxy.local:3128", proxy.Host)
+}
@@ -2,8 +2,14 @@ package cloudshare
 
 // Region in which environments are created
 type Region struct {
-	ID           string `json:"id"`
-	Name         string `json:"name"`
-	CloudName    string `json:"cloudName"`
-	FriendlyName string `json:"friendlyName"`
+	ID           RegionID `json:"id"`
+	Name         string   `json:"name"`
+	CloudName    string   `json:"cloudName"`
+	FriendlyName string   `json:"friendlyName"`
+}
+
+// Ptr returns a pointer to v, for setting optional (nullable) fields.
+// e.g. EditVMHardwareRequest{VMID: vmID, NumCPUs: cloudshare.Ptr(4)}
+func Ptr[T any](v T) *T {
+	return &v
 }
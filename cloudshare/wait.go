@@ -0,0 +1,109 @@
+package cloudshare
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// WaitOptions controls how WaitForEnvironmentStatus polls the environment.
+// All fields are optional.
+type WaitOptions struct {
+	// Interval is the delay between the first polls. Defaults to 5 seconds.
+	Interval time.Duration
+	// Backoff multiplies the interval after every poll. Values below 1 mean a fixed interval.
+	Backoff float64
+	// MaxInterval caps the interval when Backoff is used. Defaults to 1 minute.
+	MaxInterval time.Duration
+	// OnProgress is called with the environment details after every poll,
+	// e.g. to report the Progress and StatusText of each VM.
+	OnProgress func(env *EnvironmentExtended)
+}
+
+// EnvironmentStatusError is returned by WaitForEnvironmentStatus when the
+// environment reaches a terminal status other than the one waited for.
+type EnvironmentStatusError struct {
+	EnvID      EnvironmentID
+	StatusCode EnvironmentStatusCode
+	StatusText string
+	Expected   EnvironmentStatusCode
+}
+
+func (e *EnvironmentStatusError) Error() string {
+	return fmt.Sprintf("environment %s reached status %s (%s) while waiting for status %s",
+		e.EnvID, e.StatusCode, e.StatusText, e.Expected)
+}
+
+// failedWaiting returns true for terminal statuses other than the expected one, e.g. StatusCreationFailed
+// when waiting for StatusReady. It doesn't rely on CanReach, as the transition graph isn't documented by the API.
+func failedWaiting(status EnvironmentStatusCode, expected EnvironmentStatusCode) bool {
+	return status != expected && status.IsTerminal()
+}
+
+/*
+WaitForEnvironmentStatus polls the environment until it reaches the given status, and returns its details.
+
+Use ctx to set a timeout. The wait fails fast with an *EnvironmentStatusError when the environment
+reaches a terminal status (StatusCreationFailed or StatusDeleted) other than the expected one.
+
+Example:
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Minute)
+	defer cancel()
+	env, err := client.WaitForEnvironmentStatus(ctx, envID, cloudshare.StatusSuspended, &cloudshare.WaitOptions{
+		OnProgress: func(env *cloudshare.EnvironmentExtended) {
+			for _, vm := range env.Vms {
+				fmt.Printf("%s: %d%% %s\n", vm.Name, vm.Progress, vm.StatusText)
+			}
+		},
+	})
+*/
+func (c *Client) WaitForEnvironmentStatus(ctx context.Context, envID EnvironmentID, status EnvironmentStatusCode, opts *WaitOptions) (*EnvironmentExtended, error) {
+	if opts == nil {
+		opts = &WaitOptions{}
+	}
+	interval := opts.Interval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	maxInterval := opts.MaxInterval
+	if maxInterval <= 0 {
+		maxInterval = time.Minute
+	}
+
+	for {
+		env := &EnvironmentExtended{}
+		if err := c.GetEnvironmentExtendedWithContext(ctx, envID, env); err != nil {
+			return nil, err
+		}
+		if opts.OnProgress != nil {
+			opts.OnProgress(env)
+		}
+		if env.StatusCode == status {
+			return env, nil
+		}
+		if failedWaiting(env.StatusCode, status) {
+			return env, &EnvironmentStatusError{
+				EnvID:      envID,
+				StatusCode: env.StatusCode,
+				StatusText: env.StatusText,
+				Expected:   status,
+			}
+		}
+
+		if !sleepContext(ctx, interval) {
+			return env, ctx.Err()
+		}
+		if opts.Backoff > 1 {
+			interval = time.Duration(float64(interval) * opts.Backoff)
+			if interval > maxInterval {
+				interval = maxInterval
+			}
+		}
+	}
+}
+
+// WaitForReady polls the environment until it's ready (StatusReady). See WaitForEnvironmentStatus.
+func (c *Client) WaitForReady(ctx context.Context, envID EnvironmentID, opts *WaitOptions) (*EnvironmentExtended, error) {
+	return c.WaitForEnvironmentStatus(ctx, envID, StatusReady, opts)
+}
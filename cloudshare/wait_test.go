@@ -0,0 +1,78 @@
+package cloudshare
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// statusSequenceClient returns a client whose getextended calls report the given statuses in order,
+// repeating the last one
+func statusSequenceClient(t *testing.T, statuses ...EnvironmentStatusCode) (*Client, *int32) {
+	var polls int32
+	c := newLocalClient(t, func(w http.ResponseWriter, r *http.Request) {
+		require.Equal(t, "/api/v3/envs/actions/getextended", r.URL.Path)
+		i := int(atomic.AddInt32(&polls, 1)) - 1
+		if i >= len(statuses) {
+			i = len(statuses) - 1
+		}
+		fmt.Fprintf(w, `{"id": "%s", "statusCode": %d, "statusText": "status %d", "vms": [{"name": "vm1", "progress": %d}]}`,
+			r.URL.Query().Get("envId"), statuses[i], statuses[i], i*10)
+	})
+	return c, &polls
+}
+
+func TestWaitForReady(t *testing.T) {
+	c, polls := statusSequenceClient(t, StatusPreparing, StatusPreparing, StatusReady)
+	var progress []int
+	env, err := c.WaitForReady(context.Background(), "ENabc", &WaitOptions{
+		Interval: time.Millisecond,
+		OnProgress: func(env *EnvironmentExtended) {
+			progress = append(progress, env.Vms[0].Progress)
+		},
+	})
+	require.Nil(t, err)
+	assert.Equal(t, StatusReady, env.StatusCode)
+	assert.Equal(t, int32(3), atomic.LoadInt32(polls))
+	assert.Equal(t, []int{0, 10, 20}, progress)
+}
+
+func TestWaitFailsFastOnTerminalStatus(t *testing.T) {
+	c, polls := statusSequenceClient(t, StatusPreparing, StatusCreationFailed, StatusReady)
+	env, err := c.WaitForReady(context.Background(), "ENabc", &WaitOptions{Interval: time.Millisecond})
+	var statusErr *EnvironmentStatusError
+	require.True(t, errors.As(err, &statusErr))
+	assert.Equal(t, StatusCreationFailed, statusErr.StatusCode)
+	assert.Equal(t, StatusReady, statusErr.Expected)
+	assert.Equal(t, StatusCreationFailed, env.StatusCode)
+	assert.Equal(t, int32(2), atomic.LoadInt32(polls))
+}
+
+func TestWaitKeepsPollingNonTerminalStatus(t *testing.T) {
+	c, polls := statusSequenceClient(t, StatusSuspended, StatusArchived, StatusReady)
+	env, err := c.WaitForReady(context.Background(), "ENabc", &WaitOptions{Interval: time.Millisecond})
+	require.Nil(t, err, "CanReach is only advisory")
+	assert.Equal(t, StatusReady, env.StatusCode)
+	assert.Equal(t, int32(3), atomic.LoadInt32(polls))
+}
+
+func TestWaitForDeleted(t *testing.T) {
+	c, _ := statusSequenceClient(t, StatusSuspended, StatusDeleted)
+	env, err := c.WaitForEnvironmentStatus(context.Background(), "ENabc", StatusDeleted, &WaitOptions{Interval: time.Millisecond})
+	require.Nil(t, err)
+	assert.Equal(t, StatusDeleted, env.StatusCode)
+}
+
+func TestWaitTimeout(t *testing.T) {
+	c, _ := statusSequenceClient(t, StatusPreparing)
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := c.WaitForReady(ctx, "ENabc", &WaitOptions{Interval: 5 * time.Millisecond, Backoff: 2})
+	require.True(t, errors.Is(err, context.DeadlineExceeded))
+}
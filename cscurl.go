@@ -6,6 +6,7 @@ import (
 	"github.com/urfave/cli"
 	neturl "net/url"
 	"os"
+	"regexp"
 	"strings"
 )
 
@@ -30,16 +31,19 @@ func main() {
 			Value: "get",
 		},
 		cli.StringFlag{
-			Name:   "api-key",
-			Value:  "",
-			Usage:  "CloudShare API key",
-			EnvVar: "CLOUDSHARE_API_KEY",
+			Name:  "api-key",
+			Value: "",
+			Usage: "CloudShare API key. Defaults to $CLOUDSHARE_API_KEY, or the key of the credentials file profile",
 		},
 		cli.StringFlag{
-			Name:   "api-id",
-			Value:  "",
-			Usage:  "CloudShare API ID",
-			EnvVar: "CLOUDSHARE_API_ID",
+			Name:  "api-id",
+			Value: "",
+			Usage: "CloudShare API ID. Defaults to $CLOUDSHARE_API_ID, or the ID of the credentials file profile",
+		},
+		cli.StringFlag{
+			Name:  "profile, p",
+			Value: "",
+			Usage: "Credentials file (~/.cloudshare/credentials) profile. Defaults to $CLOUDSHARE_PROFILE, or \"default\"",
 		},
 		cli.BoolFlag{
 			Name:  "headers, I",
@@ -50,20 +54,25 @@ func main() {
 			Value: "",
 			Usage: "JSON document",
 		},
+		cli.StringFlag{
+			Name:  "proxy, x",
+			Value: "",
+			Usage: "Proxy URL",
+		},
+		cli.StringFlag{
+			Name:   "base-url",
+			Value:  "",
+			Usage:  "API base URL, e.g. https://use.cloudshare.com/api/v3/. Lets the URL argument be a path relative to it",
+			EnvVar: "CLOUDSHARE_BASE_URL",
+		},
+		cli.BoolFlag{
+			Name:  "insecure, k",
+			Usage: "Don't verify the server's TLS certificate",
+		},
 	}
 
 	app.Action = func(c *cli.Context) error {
-		apiKey := c.String("api-key")
-		apiID := c.String("api-id")
-		if apiKey == "" {
-			return fmt.Errorf("api-key must be set")
-		}
-
-		if apiID == "" {
-			return fmt.Errorf("api-id must be set")
-		}
-
-		if c.NArg() < 0 {
+		if c.NArg() < 1 {
 			cli.ShowAppHelp(c)
 			return fmt.Errorf("Expecting URL argument")
 		}
@@ -74,22 +83,39 @@ func main() {
 
 		showHeaders := c.Bool("headers")
 
-		client := &cs.Client{
-			APIKey: apiKey,
-			APIID:  apiID,
-			Tags:   "cscurl",
+		httpClient, err := cs.NewHTTPClient(cs.HTTPClientOptions{
+			ProxyURL:           c.String("proxy"),
+			InsecureSkipVerify: c.Bool("insecure"),
+		})
+		if err != nil {
+			return err
 		}
 
 		data := c.String("data")
-		parsed, err := neturl.Parse(url)
+		baseURL, path, query, err := splitAPIURL(url, c.String("base-url"))
+		if err != nil {
+			return err
+		}
+
+		opts := []cs.Option{
+			cs.WithTags("cscurl"),
+			cs.WithHTTPClient(httpClient),
+			cs.WithBaseURL(baseURL),
+		}
+		if apiKey, apiID := c.String("api-key"), c.String("api-id"); apiKey != "" || apiID != "" {
+			opts = append(opts, cs.WithCredentials(apiID, apiKey))
+		} else if profile := c.String("profile"); profile != "" {
+			opts = append(opts, cs.WithProfile(profile))
+		}
+		client, err := cs.NewClient(opts...)
 		if err != nil {
 			return err
 		}
-		query := parsed.Query()
-		client.APIHost = parsed.Host
-		path := strings.Replace(parsed.Path, "api/v3/", "", 1)
 
 		response, err := client.Request(method, path, &query, &data)
+		if response == nil {
+			return err
+		}
 		if showHeaders {
 			fmt.Printf("Status code: %d\n", response.StatusCode)
 			for key, value := range response.Headers {
@@ -97,10 +123,9 @@ func main() {
 					fmt.Printf("%s: %s\n", key, x)
 				}
 			}
-			fmt.Println("\n\n")
+			fmt.Print("\n\n\n")
 		}
 		if err != nil {
-			fmt.Println(err)
 			fmt.Println(string(response.Body))
 			return err
 
@@ -110,5 +135,53 @@ func main() {
 		return nil
 	}
 
-	app.Run(os.Args)
+	if err := app.Run(os.Args); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+var apiBasePattern = regexp.MustCompile(`^(.*?/api/v\d+/)(.*)$`)
+
+// splitAPIURL splits a URL argument into the API base URL, the path relative to it and the query.
+// The argument is either an absolute URL, or a path relative to baseURL.
+func splitAPIURL(rawURL string, baseURL string) (string, string, neturl.Values, error) {
+	parsed, err := neturl.Parse(rawURL)
+	if err != nil {
+		return "", "", nil, err
+	}
+	query := parsed.Query()
+	parsed.RawQuery = ""
+	parsed.Fragment = ""
+
+	if !parsed.IsAbs() {
+		if baseURL == "" {
+			baseURL = cs.DefaultBaseURL
+		}
+		base, err := cs.ParseBaseURL(baseURL)
+		if err != nil {
+			return "", "", nil, err
+		}
+		return base.String(), strings.TrimLeft(parsed.Path, "/"), query, nil
+	}
+
+	full := parsed.String()
+	if baseURL != "" {
+		base, err := cs.ParseBaseURL(baseURL)
+		if err != nil {
+			return "", "", nil, err
+		}
+		if strings.HasPrefix(full, base.String()) {
+			return base.String(), strings.TrimPrefix(full, base.String()), query, nil
+		}
+	}
+
+	match := apiBasePattern.FindStringSubmatch(full)
+	if match == nil {
+		return "", "", nil, fmt.Errorf("can't find the API version in %s, use --base-url", rawURL)
+	}
+	if _, err := cs.ParseBaseURL(match[1]); err != nil {
+		return "", "", nil, err
+	}
+	return match[1], match[2], query, nil
 }